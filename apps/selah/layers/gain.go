@@ -0,0 +1,96 @@
+package layers
+
+import (
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/jdginn/arpad/devices/motu"
+	"github.com/jdginn/arpad/devices/xtouch"
+
+	mode "github.com/jdginn/arpad/apps/selah/modemanager"
+)
+
+const (
+	// MOTU_INPUT_BANK is the MOTU input bank whose channels line up with the surface's strips.
+	MOTU_INPUT_BANK = 0
+	// TRIM_MIN_DB and TRIM_MAX_DB bound the trim of the MOTU's mic preamps.
+	TRIM_MIN_DB = 0
+	TRIM_MAX_DB = 53
+)
+
+// isRecordMode reports whether m is Record mode or one of its submodes, where the surface controls the audio
+// interface instead of the DAW.
+func isRecordMode(m mode.Mode) bool {
+	return slices.Contains(recordModes, m)
+}
+
+var recordModes = []mode.Mode{mode.RECORD, mode.RECORD_SELECTED_TRACK_SENDS, mode.RECORD_SELECTED_OUTPUT_RECEIVES, mode.RECORD_SELECTED_AUX_RECEIVES}
+
+// FineGain trims the gain of the selected MOTU input with the jog wheel while in Record mode, one dB per detent.
+// Pressing a strip's Select button in Record mode selects the input on that strip.
+type FineGain struct {
+	*Devices
+	*mode.Manager
+
+	mux      sync.Mutex
+	selected motu.PathInputChannel
+	trims    map[int64]int64
+	trim     *xtouch.Accumulator
+}
+
+func NewFineGain(d Devices, m *mode.Manager) *FineGain {
+	g := &FineGain{
+		Devices:  &d,
+		Manager:  m,
+		selected: motu.PathInputChannel{BankIndex: MOTU_INPUT_BANK},
+		trims:    make(map[int64]int64),
+		trim:     xtouch.NewAccumulator(TRIM_MIN_DB, TRIM_MAX_DB, TRIM_MAX_DB-TRIM_MIN_DB),
+	}
+	for i := int64(0); i < int64(g.Surface.Len()); i++ {
+		g.MOTU.Ext.InputTrim.Bind(motu.PathInputChannel{BankIndex: MOTU_INPUT_BANK, ChannelIndex: i}, func(v int64) error {
+			g.mux.Lock()
+			defer g.mux.Unlock()
+			g.trims[i] = v
+			if i == g.selected.ChannelIndex {
+				g.trim.Set(float64(v))
+			}
+			return nil
+		})
+		g.Surface.Strip(int(i)).Select.On.Bind(func() error {
+			if !isRecordMode(g.CurrMode()) {
+				return nil
+			}
+			g.mux.Lock()
+			defer g.mux.Unlock()
+			g.selected.ChannelIndex = i
+			g.trim.Set(float64(g.trims[i]))
+			return nil
+		})
+	}
+	// The wheel moves the accumulator in every mode, so pick up from the selected input's trim on entering Record mode.
+	for _, md := range recordModes {
+		g.OnTransition(md, func() error {
+			g.mux.Lock()
+			defer g.mux.Unlock()
+			g.trim.Set(float64(g.trims[g.selected.ChannelIndex]))
+			return nil
+		})
+	}
+	g.XTouch.JogWheel.Accumulate(g.trim, func(v float64) error {
+		if !isRecordMode(g.CurrMode()) {
+			return nil
+		}
+		g.mux.Lock()
+		p := g.selected
+		db := int64(math.Round(v))
+		if db == g.trims[p.ChannelIndex] {
+			g.mux.Unlock()
+			return nil
+		}
+		g.trims[p.ChannelIndex] = db
+		g.mux.Unlock()
+		return g.MOTU.Ext.InputTrim.Set(p, db)
+	})
+	return g
+}
@@ -0,0 +1,56 @@
+package layers
+
+import (
+	"math"
+	"sync"
+
+	"github.com/jdginn/arpad/devices/xtouch"
+
+	mode "github.com/jdginn/arpad/apps/selah/modemanager"
+)
+
+const (
+	// JOG_SECONDS_PER_STEP is how far one detent of the jog wheel moves the playhead when turned slowly.
+	JOG_SECONDS_PER_STEP = 0.05
+	// JOG_ACCELERATION_KNEE is the jog wheel speed, in detents per second, above which each detent moves the
+	// playhead further in proportion to the speed.
+	JOG_ACCELERATION_KNEE = 8.0
+)
+
+// Playhead moves Reaper's playhead with the jog wheel: in shuttle mode it jumps the edit cursor, accelerating as the
+// wheel spins faster, and in scrub mode it scrubs audio. In Record mode the wheel belongs to FineGain instead.
+type Playhead struct {
+	*Devices
+	*mode.Manager
+
+	mux  sync.Mutex
+	time float64
+}
+
+func NewPlayhead(d Devices, m *mode.Manager) *Playhead {
+	p := &Playhead{
+		Devices: &d,
+		Manager: m,
+	}
+	p.Reaper.Time.Bind(func(t float64) error {
+		p.mux.Lock()
+		defer p.mux.Unlock()
+		p.time = t
+		return nil
+	})
+	p.XTouch.JogWheel.Bind(func(e xtouch.JogEvent) error {
+		if isRecordMode(p.CurrMode()) {
+			return nil
+		}
+		if e.Mode == xtouch.Scrub {
+			return p.Reaper.Scrub.Set(float64(e.Delta))
+		}
+		accel := math.Max(1, math.Abs(e.Velocity)/JOG_ACCELERATION_KNEE)
+		p.mux.Lock()
+		p.time = math.Max(0, p.time+float64(e.Delta)*JOG_SECONDS_PER_STEP*accel)
+		t := p.time
+		p.mux.Unlock()
+		return p.Reaper.Time.Set(t)
+	})
+	return p
+}
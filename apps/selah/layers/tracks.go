@@ -9,6 +9,7 @@ import (
 
 	"github.com/hypebeast/go-osc/osc"
 
+	"github.com/jdginn/arpad/devices/motu"
 	"github.com/jdginn/arpad/devices/reaper"
 	"github.com/jdginn/arpad/devices/xtouch"
 	"github.com/jdginn/arpad/logging"
@@ -25,14 +26,18 @@ func init() {
 
 const (
 	FADER_EPSILON float64 = 0.001
-	NUM_CHANNELS  int64   = 8
+	// PAN_RESOLUTION is the number of encoder steps that sweep pan from hard left to hard right.
+	PAN_RESOLUTION = 64
 )
 
 type GUID = string
 
 type Devices struct {
 	XTouch *xtouch.XTouchDefault
-	Reaper *reaper.Reaper
+	// Surface spans the channel strips of the X-Touch and any extenders joined to it.
+	Surface *xtouch.SurfaceGroup
+	Reaper  *reaper.Reaper
+	MOTU    *motu.MOTU
 }
 
 type TrackManager struct {
@@ -67,7 +72,7 @@ func (t *TrackManager) addTrack(guid GUID) {
 }
 
 func (m *TrackManager) resetSurfaceChannel(idx int64) {
-	xt := m.XTouch.Channels[idx]
+	xt := m.Surface.Strip(int(idx))
 	err := xt.Fader.Set(0)
 	err = errors.Join(err, xt.Encoder.Ring.Set(0))
 	err = errors.Join(err, xt.Mute.LED.Set(false))
@@ -90,9 +95,9 @@ func (t *TrackManager) deleteTrack(guid GUID) {
 		t.DeleteGuid(guid)
 		delete(t.tracks, guid)
 	}
-	// If after deleting the track, we have fewer than NUM_CHANNELS tracks, reset any channels on the surface that
-	// are no longer in use.
-	for idx := int64(0); idx < NUM_CHANNELS; idx++ {
+	// If after deleting the track, we have fewer tracks than the surface has channels, reset any channels on the
+	// surface that are no longer in use.
+	for idx := int64(0); idx < int64(t.Surface.Len()); idx++ {
 		if _, ok := t.BySurfIdx(idx).MaybeGuid(); !ok {
 			appLog.Debug("Resetting surface for deleted track", slog.Int64("idx", idx), slog.String("guid", guid))
 			t.resetSurfaceChannel(idx)
@@ -116,7 +121,7 @@ func (t *TrackManager) listenForNewTracks() {
 
 func (m *TrackManager) AddHardwareTrack(idx int64) {
 	// Select
-	m.XTouch.Channels[idx].Select.On.Bind(func() (errs error) {
+	m.Surface.Strip(int(idx)).Select.On.Bind(func() (errs error) {
 		if track, ok := m.getTrackAtIdx(idx); ok {
 			switch m.CurrMode() {
 			case mode.MIX:
@@ -131,7 +136,7 @@ func (m *TrackManager) AddHardwareTrack(idx int64) {
 		return nil
 	})
 	// REC
-	m.XTouch.Channels[idx].Rec.On.Bind(func() error {
+	m.Surface.Strip(int(idx)).Rec.On.Bind(func() error {
 		if track, ok := m.getTrackAtIdx(idx); ok {
 			switch m.CurrMode() {
 			case mode.MIX:
@@ -142,7 +147,7 @@ func (m *TrackManager) AddHardwareTrack(idx int64) {
 		return nil
 	})
 	// SOLO
-	m.XTouch.Channels[idx].Solo.On.Bind(func() error {
+	m.Surface.Strip(int(idx)).Solo.On.Bind(func() error {
 		if track, ok := m.getTrackAtIdx(idx); ok {
 			switch m.CurrMode() {
 			case mode.MIX:
@@ -153,7 +158,7 @@ func (m *TrackManager) AddHardwareTrack(idx int64) {
 		return nil
 	})
 	// MUTE
-	m.XTouch.Channels[idx].Mute.On.Bind(func() error {
+	m.Surface.Strip(int(idx)).Mute.On.Bind(func() error {
 		if track, ok := m.getTrackAtIdx(idx); ok {
 			switch m.CurrMode() {
 			case mode.MIX:
@@ -165,7 +170,7 @@ func (m *TrackManager) AddHardwareTrack(idx int64) {
 		return nil
 	})
 	// Fader
-	m.XTouch.Channels[idx].Fader.Bind(func(v uint16) error {
+	m.Surface.Strip(int(idx)).Fader.Bind(func(v uint16) error {
 		if track, ok := m.getTrackAtIdx(idx); ok {
 			switch m.CurrMode() {
 			case mode.MIX:
@@ -199,15 +204,32 @@ func (m *TrackManager) AddHardwareTrack(idx int64) {
 		return nil
 	})
 	// Pan
-	m.XTouch.Channels[idx].Encoder.Bind(func(v uint8) error {
+	//
+	// The encoder reports relative turns, so each turn moves the track's current pan. Holding SHIFT pans finely.
+	pan := xtouch.NewAccumulator(0, 1, PAN_RESOLUTION).FineWhile(m.XTouch.Modify.SHIFT)
+	m.Surface.Strip(int(idx)).Encoder.BindDelta(func(steps int) error {
 		if t, ok := m.getTrackAtIdx(idx); ok {
 			switch m.CurrMode() {
 			case mode.MIX:
-				t.pan = float64(v) / float64(math.MaxUint8)
+				pan.Set(t.pan)
+				v, changed := pan.Add(steps)
+				if !changed {
+					return nil
+				}
+				t.pan = v
 				return m.Reaper.Track(m.BySurfIdx(idx).Guid()).Pan.Set(t.pan)
 			case mode.MIX_SELECTED_TRACK_SENDS:
-				t.pan = float64(v) / float64(math.MaxUint8)
-				return m.Reaper.Track(m.selectedTrack.guid).Send(idx).Pan.Set(t.volume)
+				send, ok := t.sends[idx]
+				if !ok {
+					return nil
+				}
+				pan.Set(send.pan)
+				v, changed := pan.Add(steps)
+				if !changed {
+					return nil
+				}
+				send.pan = v
+				return m.Reaper.Track(m.selectedTrack.guid).Send(idx).Pan.Set(v)
 			}
 		}
 		return nil
@@ -243,7 +265,7 @@ func intToNormFloat(val uint16) float64 {
 }
 
 type TrackData struct {
-	x      *xtouch.XTouchDefault
+	s      *xtouch.SurfaceGroup
 	r      *reaper.Reaper
 	m      *TrackManager
 	guid   GUID
@@ -259,7 +281,7 @@ type TrackData struct {
 
 func NewTrackData(m *TrackManager, guid GUID) *TrackData {
 	t := &TrackData{
-		x:     m.XTouch,
+		s:     m.Surface,
 		r:     m.Reaper,
 		guid:  guid,
 		sends: make(map[int64]*trackSendData),
@@ -278,7 +300,7 @@ func NewTrackData(m *TrackManager, guid GUID) *TrackData {
 		t.name = v
 		switch m.CurrMode() {
 		case mode.MIX:
-			return m.XTouch.Channels[m.ByGuid(guid).SurfIdx()].Scribble.
+			return m.Surface.Strip(int(m.ByGuid(guid).SurfIdx())).Scribble.
 				ChangeTopMessage(t.name).
 				Set()
 		}
@@ -288,7 +310,7 @@ func NewTrackData(m *TrackManager, guid GUID) *TrackData {
 		appLog.Debug("Track color changed", slog.String("guid", guid), slog.Int64("color", v))
 		switch m.CurrMode() {
 		case mode.MIX:
-			return m.XTouch.Channels[m.ByGuid(guid).SurfIdx()].Scribble.ChangeColor(xtouch.Red).Set() // TODO: need to get colors from v
+			return m.Surface.Strip(int(m.ByGuid(guid).SurfIdx())).Scribble.ChangeColor(xtouch.Red).Set() // TODO: need to get colors from v
 		}
 		return nil
 	})
@@ -299,11 +321,11 @@ func NewTrackData(m *TrackManager, guid GUID) *TrackData {
 		switch m.CurrMode() {
 		case mode.MIX:
 			// Turn off select button for the previously selected track
-			errs = errors.Join(errs, m.XTouch.Channels[m.ByGuid(m.selectedTrack.guid).SurfIdx()].
+			errs = errors.Join(errs, m.Surface.Strip(int(m.ByGuid(m.selectedTrack.guid).SurfIdx())).
 				Select.LED.Set(!v))
 			m.selectedTrack = t
 			// Turn on select button for the newly selected track
-			errs = errors.Join(errs, t.x.Channels[m.ByGuid(guid).SurfIdx()].
+			errs = errors.Join(errs, t.s.Strip(int(m.ByGuid(guid).SurfIdx())).
 				Select.LED.Set(v))
 		}
 		return errs
@@ -314,7 +336,7 @@ func NewTrackData(m *TrackManager, guid GUID) *TrackData {
 		t.rec = v
 		switch m.CurrMode() {
 		case mode.MIX:
-			return t.x.Channels[m.ByGuid(guid).SurfIdx()].
+			return t.s.Strip(int(m.ByGuid(guid).SurfIdx())).
 				Rec.LED.Set(v)
 		}
 		return nil
@@ -325,7 +347,7 @@ func NewTrackData(m *TrackManager, guid GUID) *TrackData {
 		t.solo = v
 		switch m.CurrMode() {
 		case mode.MIX:
-			return t.x.Channels[m.ByGuid(guid).SurfIdx()].
+			return t.s.Strip(int(m.ByGuid(guid).SurfIdx())).
 				Solo.LED.Set(v)
 		}
 		return nil
@@ -336,7 +358,7 @@ func NewTrackData(m *TrackManager, guid GUID) *TrackData {
 		t.mute = v
 		switch m.CurrMode() {
 		case mode.MIX:
-			return t.x.Channels[m.ByGuid(guid).SurfIdx()].
+			return t.s.Strip(int(m.ByGuid(guid).SurfIdx())).
 				Mute.LED.Set(v)
 		}
 		return nil
@@ -346,7 +368,7 @@ func NewTrackData(m *TrackManager, guid GUID) *TrackData {
 		t.volume = v
 		switch m.CurrMode() {
 		case mode.MIX:
-			return t.x.Channels[m.ByGuid(guid).SurfIdx()].Fader.Set(normFloatToInt(v))
+			return t.s.Strip(int(m.ByGuid(guid).SurfIdx())).Fader.Set(normFloatToInt(v))
 		}
 		return nil
 	})
@@ -355,7 +377,7 @@ func NewTrackData(m *TrackManager, guid GUID) *TrackData {
 		t.pan = v
 		switch m.CurrMode() {
 		case mode.MIX:
-			return t.x.Channels[m.ByGuid(guid).SurfIdx()].Encoder.Ring.Set(v) // TODO: verify
+			return t.s.Strip(int(m.ByGuid(guid).SurfIdx())).Encoder.Ring.Set(v) // TODO: verify
 		}
 		return nil
 	})
@@ -364,7 +386,7 @@ func NewTrackData(m *TrackManager, guid GUID) *TrackData {
 }
 
 func (t *TrackData) TransitionMix() (errs error) {
-	xt := t.x.Channels[t.m.ByGuid(t.guid).SurfIdx()]
+	xt := t.s.Strip(int(t.m.ByGuid(t.guid).SurfIdx()))
 	return errors.Join(errs,
 		xt.Fader.Set(normFloatToInt(t.volume)),
 		xt.Encoder.Ring.Set(t.pan),
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
-	"time"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"gitlab.com/gomidi/midi/v2"
 	"gitlab.com/gomidi/midi/v2/drivers"
@@ -11,6 +15,7 @@ import (
 	_ "gitlab.com/gomidi/midi/v2/drivers/rtmididrv" // autoregisters driver
 
 	"github.com/jdginn/arpad/devices"
+	motulib "github.com/jdginn/arpad/devices/motu"
 	reaperlib "github.com/jdginn/arpad/devices/reaper"
 	xtouchlib "github.com/jdginn/arpad/devices/xtouch"
 	"github.com/jdginn/arpad/logging"
@@ -36,13 +41,14 @@ import (
 // -> This output all input tracks
 // -> This aux all input tracks
 // In record mode, encoders are mapped to gain by default. Push in to control pan.
+// In record mode, the jog wheel finely trims the gain of the input on the last selected strip.
 //
 // # Timecode display lists the current mode using ascii-to-7seg characters
 //
 // # Mode selection is mapped to Encoder Assign
 //
 // The following buttons are active in every mode:
-// - Modify, Utility, Automation, Transport buttons and jog wheel control the DAW at all times.
+// - Modify, Utility, Automation, Transport buttons control the DAW at all times, as does the jog wheel outside record mode.
 // - Talkback (mapped to Display button)
 // - Global mute (mapped to Global View)
 // - Control room monitoring selection (main monitors, mono mixcube, nearfield monitors, headphones-only, other?) mapped to View bttons (excluding Global View)
@@ -53,9 +59,14 @@ const (
 	OSC_REAPER_PORT = 9091
 	OSC_ARPAD_IP    = "0.0.0.0"
 	OSC_ARPAD_PORT  = 9090
+	MOTU_URL        = "http://localhost:1280/datastore"
 )
 
-const DEVICE_TRACKS = 8
+// EXTENDER_PORT is the name shared by the MIDI ports of X-Touch Extenders.
+const EXTENDER_PORT = "X-Touch-Ext"
+
+// EXTENDERS_LEFT places the extenders to the left of the X-Touch instead of to its right.
+const EXTENDERS_LEFT = false
 
 var log *slog.Logger
 
@@ -104,6 +115,23 @@ func getMidiPorts() (in drivers.In, out drivers.Out, err error) {
 	return in, out, nil
 }
 
+// getExtenderPorts returns the MIDI ports of every connected X-Touch Extender, pairing inputs and outputs in the
+// order the system lists them.
+func getExtenderPorts() (ins []drivers.In, outs []drivers.Out) {
+	for _, in := range midi.GetInPorts() {
+		if strings.Contains(in.String(), EXTENDER_PORT) {
+			ins = append(ins, in)
+		}
+	}
+	for _, out := range midi.GetOutPorts() {
+		if strings.Contains(out.String(), EXTENDER_PORT) {
+			outs = append(outs, out)
+		}
+	}
+	n := min(len(ins), len(outs))
+	return ins[:n], outs[:n]
+}
+
 func main() {
 	defer midi.CloseDriver()
 	in, out, err := getMidiPorts()
@@ -114,27 +142,54 @@ func main() {
 	log.Info("Starting Selah app...")
 
 	xtouch := xtouchlib.New(devices.NewMidiDevice(in, out))
+	units := []xtouchlib.Unit{xtouch}
+	extIns, extOuts := getExtenderPorts()
+	for i := range extIns {
+		log.Info("Found X-Touch Extender", slog.String("port", extIns[i].String()))
+		units = append(units, xtouchlib.NewExtender(devices.NewMidiDevice(extIns[i], extOuts[i])))
+	}
+	surface := xtouchlib.NewSurfaceGroup(units...)
+	if EXTENDERS_LEFT {
+		order := make([]int, len(units))
+		for i := range order {
+			order[i] = (i + 1) % len(units)
+		}
+		if err := surface.Reorder(order...); err != nil {
+			panic(err)
+		}
+	}
 
 	reaper := reaperlib.NewReaper(devices.NewOscDevice(OSC_ARPAD_IP, OSC_ARPAD_PORT, OSC_REAPER_IP, OSC_REAPER_PORT, reaperlib.NewDispatcher()))
 
+	motuDatastore := motulib.NewHTTPDatastore(MOTU_URL)
+	motu := motulib.NewMOTU(&motuDatastore)
+
 	modeManager := mode.NewManager(xtouch, reaper)
 	layers.NewEncoderAssign(modeManager)
-	trackManager := layers.NewTrackManager(layers.Devices{
-		XTouch: xtouch,
-		Reaper: reaper,
-	}, modeManager)
-	for i := int64(0); i < DEVICE_TRACKS; i++ {
+	devs := layers.Devices{
+		XTouch:  xtouch,
+		Surface: surface,
+		Reaper:  reaper,
+		MOTU:    motu,
+	}
+	trackManager := layers.NewTrackManager(devs, modeManager)
+	for i := int64(0); i < int64(surface.Len()); i++ {
 		trackManager.AddHardwareTrack(i)
 	}
+	layers.NewPlayhead(devs, modeManager)
+	layers.NewFineGain(devs, modeManager)
 	if err := modeManager.SetMode(mode.MIX); err != nil {
 		log.Error("Failed to set initial mode", "error", err)
 		return
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	go reaper.Run()
 	log.Info("Reaper is running...")
-	go xtouch.Run()
-	log.Info("Xtouch is running...")
-
-	time.Sleep(time.Second * 1000)
+	log.Info("Xtouch is running...", slog.Int("strips", surface.Len()))
+	if err := surface.Run(ctx); err != nil {
+		log.Error("Xtouch stopped", "error", err)
+	}
 }
@@ -20,6 +20,7 @@ type MockMIDIPort struct {
 
 	// For testing error conditions
 	shouldError bool
+	openErr     error
 
 	isOpen bool
 }
@@ -33,8 +34,11 @@ func NewMockMIDIPort() *MockMIDIPort {
 
 func (m *MockMIDIPort) Open() error {
 	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.openErr != nil {
+		return m.openErr
+	}
 	m.isOpen = true
-	m.mu.Unlock()
 	return nil
 }
 
@@ -150,3 +154,106 @@ func (m *MockMIDIPort) SetError(shouldError bool) {
 	m.shouldError = shouldError
 	m.mu.Unlock()
 }
+
+// SetOpenError configures the mock to fail to open with the given error. Pass nil to clear.
+func (m *MockMIDIPort) SetOpenError(err error) {
+	m.mu.Lock()
+	m.openErr = err
+	m.mu.Unlock()
+}
+
+// MockPortLookup implements devices.PortLookup over a pair of mock ports that can be unplugged and replugged.
+type MockPortLookup struct {
+	mu  sync.Mutex
+	in  *MockMIDIPort
+	out *MockMIDIPort
+}
+
+func NewMockPortLookup(in, out *MockMIDIPort) *MockPortLookup {
+	return &MockPortLookup{in: in, out: out}
+}
+
+// Unplug makes both ports disappear from the lookup.
+func (l *MockPortLookup) Unplug() {
+	l.mu.Lock()
+	l.in, l.out = nil, nil
+	l.mu.Unlock()
+}
+
+// Plug makes the given ports available, as when a device is reconnected.
+func (l *MockPortLookup) Plug(in, out *MockMIDIPort) {
+	l.mu.Lock()
+	l.in, l.out = in, out
+	l.mu.Unlock()
+}
+
+func (l *MockPortLookup) FindIn(name string) (drivers.In, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.in == nil || l.in.String() != name {
+		return nil, errors.New("no such input port: " + name)
+	}
+	return l.in, nil
+}
+
+func (l *MockPortLookup) FindOut(name string) (drivers.Out, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.out == nil || l.out.String() != name {
+		return nil, errors.New("no such output port: " + name)
+	}
+	return l.out, nil
+}
+
+// MockVirtualDriver is a drivers.Driver that creates virtual ports backed by MockMIDIPorts. It lists no hardware
+// ports.
+type MockVirtualDriver struct {
+	mu   sync.Mutex
+	ins  []*MockMIDIPort
+	outs []*MockMIDIPort
+}
+
+func NewMockVirtualDriver() *MockVirtualDriver {
+	return &MockVirtualDriver{}
+}
+
+func (d *MockVirtualDriver) OpenVirtualIn(name string) (drivers.In, error) {
+	p := NewMockMIDIPort()
+	p.Open()
+	d.mu.Lock()
+	d.ins = append(d.ins, p)
+	d.mu.Unlock()
+	return p, nil
+}
+
+func (d *MockVirtualDriver) OpenVirtualOut(name string) (drivers.Out, error) {
+	p := NewMockMIDIPort()
+	p.Open()
+	d.mu.Lock()
+	d.outs = append(d.outs, p)
+	d.mu.Unlock()
+	return p, nil
+}
+
+// VirtualPorts returns every input and output port created so far, oldest first.
+func (d *MockVirtualDriver) VirtualPorts() (ins, outs []*MockMIDIPort) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]*MockMIDIPort(nil), d.ins...), append([]*MockMIDIPort(nil), d.outs...)
+}
+
+func (d *MockVirtualDriver) Ins() ([]drivers.In, error) {
+	return nil, nil
+}
+
+func (d *MockVirtualDriver) Outs() ([]drivers.Out, error) {
+	return nil, nil
+}
+
+func (d *MockVirtualDriver) String() string {
+	return "MockVirtualDriver"
+}
+
+func (d *MockVirtualDriver) Close() error {
+	return nil
+}
@@ -0,0 +1,28 @@
+package devicestesting
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// RunDevice calls run in the background and waits for connected to report true. The returned function cancels
+// run and waits for it to return; run must return nil.
+//
+//	defer devicestesting.RunDevice(t, d.Run, d.IsConnected)()
+func RunDevice(t *testing.T, run func(context.Context) error, connected func() bool) func() {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, run(ctx))
+	}()
+	assert.Eventually(t, connected, time.Second, time.Millisecond, "device should connect")
+	return func() {
+		cancel()
+		<-done
+	}
+}
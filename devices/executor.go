@@ -0,0 +1,196 @@
+package devices
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jdginn/arpad/logging"
+)
+
+var execLog *slog.Logger
+
+func init() {
+	execLog = logging.Get(logging.APP)
+}
+
+// QueuePolicy decides what happens when a callback is submitted to an endpoint queue that is already busy.
+type QueuePolicy uint8
+
+const (
+	// Block queues every call. When the queue is full the submitter, usually a device's receive goroutine, waits
+	// for room.
+	Block QueuePolicy = iota
+	// DropNewest queues calls until the queue is full and then discards new ones.
+	DropNewest
+	// Coalesce keeps at most one pending call, replacing it with each newer one. Use it for continuous controls
+	// such as faders where only the latest value matters.
+	Coalesce
+)
+
+// ExecutorStats counts what an Executor has done with the calls submitted to it.
+type ExecutorStats struct {
+	// Submitted is the number of calls submitted.
+	Submitted uint64
+	// Completed is the number of calls that have run.
+	Completed uint64
+	// Dropped is the number of calls discarded by DropNewest queues.
+	Dropped uint64
+	// Coalesced is the number of pending calls replaced by newer ones in Coalesce queues.
+	Coalesced uint64
+	// Blocked is the number of submissions that had to wait for room in a Block queue.
+	Blocked uint64
+	// Pending is the number of calls currently queued or running.
+	Pending uint64
+}
+
+// Executor runs bound callbacks off the receive goroutine, on a bounded queue per endpoint.
+//
+// Calls on one endpoint run in order, one at a time; calls on different endpoints run concurrently. A slow
+// callback therefore only delays its own endpoint instead of stalling all input from its device.
+//
+// Wrap a callback with Async or AsyncFunc before binding it:
+//
+//	d.CC(0, 7).Bind(devices.Async(exec, devices.Coalesce, setVolume))
+type Executor struct {
+	depth int
+
+	submitted, completed, dropped, coalesced, blocked atomic.Uint64
+
+	// mu guards pending, which Wait uses to find out when all queues are idle.
+	mu      sync.Mutex
+	idle    *sync.Cond
+	pending uint64
+}
+
+// NewExecutor returns an Executor whose queues hold up to depth pending calls each.
+func NewExecutor(depth int) *Executor {
+	e := &Executor{depth: max(depth, 1)}
+	e.idle = sync.NewCond(&e.mu)
+	return e
+}
+
+// Stats returns a snapshot of the executor's counters.
+func (e *Executor) Stats() ExecutorStats {
+	e.mu.Lock()
+	pending := e.pending
+	e.mu.Unlock()
+	return ExecutorStats{
+		Submitted: e.submitted.Load(),
+		Completed: e.completed.Load(),
+		Dropped:   e.dropped.Load(),
+		Coalesced: e.coalesced.Load(),
+		Blocked:   e.blocked.Load(),
+		Pending:   pending,
+	}
+}
+
+// Wait blocks until every queued call has run.
+func (e *Executor) Wait() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for e.pending > 0 {
+		e.idle.Wait()
+	}
+}
+
+func (e *Executor) addPending(delta int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if delta < 0 {
+		e.pending -= uint64(-delta)
+	} else {
+		e.pending += uint64(delta)
+	}
+	if e.pending == 0 {
+		e.idle.Broadcast()
+	}
+}
+
+// Async wraps callback so that each call is queued and run by e according to policy. Every call to Async creates
+// a new queue, so wrap once per endpoint.
+//
+// The returned function always returns nil; errors from callback are logged when it runs.
+func Async[T any](e *Executor, policy QueuePolicy, callback func(T) error) func(T) error {
+	q := e.newQueue(policy)
+	return func(v T) error {
+		q.submit(func() error { return callback(v) })
+		return nil
+	}
+}
+
+// AsyncFunc is Async for callbacks that take no value, such as button presses.
+func AsyncFunc(e *Executor, policy QueuePolicy, callback func() error) func() error {
+	q := e.newQueue(policy)
+	return func() error {
+		q.submit(callback)
+		return nil
+	}
+}
+
+// execQueue is the queue of pending calls for a single endpoint. A worker goroutine drains it while it is
+// non-empty.
+type execQueue struct {
+	e      *Executor
+	policy QueuePolicy
+
+	mu      sync.Mutex
+	space   *sync.Cond
+	calls   []func() error
+	running bool
+}
+
+func (e *Executor) newQueue(policy QueuePolicy) *execQueue {
+	q := &execQueue{e: e, policy: policy}
+	q.space = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *execQueue) submit(call func() error) {
+	q.e.submitted.Add(1)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	switch {
+	case q.policy == Coalesce && len(q.calls) > 0:
+		q.calls[len(q.calls)-1] = call
+		q.e.coalesced.Add(1)
+		return
+	case q.policy == DropNewest && len(q.calls) >= q.e.depth:
+		q.e.dropped.Add(1)
+		return
+	case q.policy == Block && len(q.calls) >= q.e.depth:
+		q.e.blocked.Add(1)
+		for len(q.calls) >= q.e.depth {
+			q.space.Wait()
+		}
+	}
+	q.calls = append(q.calls, call)
+	q.e.addPending(1)
+	if !q.running {
+		q.running = true
+		go q.drain()
+	}
+}
+
+func (q *execQueue) drain() {
+	for {
+		q.mu.Lock()
+		if len(q.calls) == 0 {
+			q.running = false
+			q.mu.Unlock()
+			return
+		}
+		call := q.calls[0]
+		q.calls[0] = nil
+		q.calls = q.calls[1:]
+		q.space.Signal()
+		q.mu.Unlock()
+
+		if err := call(); err != nil {
+			execLog.Error("asynchronous callback failed", slog.Any("err", err))
+		}
+		q.e.completed.Add(1)
+		q.e.addPending(-1)
+	}
+}
@@ -0,0 +1,120 @@
+package devices_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	midi "gitlab.com/gomidi/midi/v2"
+
+	"github.com/jdginn/arpad/devices"
+	devtest "github.com/jdginn/arpad/devices/devicestesting"
+)
+
+// gate blocks callbacks until opened so that tests can fill queues deterministically.
+type gate struct {
+	started chan struct{}
+	open    chan struct{}
+}
+
+func newGate() *gate {
+	return &gate{started: make(chan struct{}, 1), open: make(chan struct{})}
+}
+
+func (g *gate) wait() {
+	select {
+	case g.started <- struct{}{}:
+	default:
+	}
+	<-g.open
+}
+
+func TestExecutorPolicies(t *testing.T) {
+	assert := assert.New(t)
+
+	run := func(policy devices.QueuePolicy, n int) ([]int, devices.ExecutorStats) {
+		e := devices.NewExecutor(2)
+		g := newGate()
+		var mu sync.Mutex
+		var got []int
+		f := devices.Async(e, policy, func(v int) error {
+			g.wait()
+			mu.Lock()
+			got = append(got, v)
+			mu.Unlock()
+			return nil
+		})
+		assert.NoError(f(0))
+		<-g.started
+		for i := 1; i < n; i++ {
+			if policy == devices.Block && i == 3 {
+				// The queue is full, so this submission waits until the worker is released.
+				go func() {
+					time.Sleep(20 * time.Millisecond)
+					close(g.open)
+				}()
+			}
+			assert.NoError(f(i))
+		}
+		if policy != devices.Block {
+			close(g.open)
+		}
+		e.Wait()
+		return got, e.Stats()
+	}
+
+	got, stats := run(devices.Coalesce, 100)
+	assert.Equal([]int{0, 99}, got, "only the latest pending value should run")
+	assert.Equal(devices.ExecutorStats{Submitted: 100, Completed: 2, Coalesced: 98}, stats)
+
+	got, stats = run(devices.DropNewest, 10)
+	assert.Equal([]int{0, 1, 2}, got)
+	assert.Equal(devices.ExecutorStats{Submitted: 10, Completed: 3, Dropped: 7}, stats)
+
+	got, stats = run(devices.Block, 10)
+	assert.Equal([]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, got)
+	assert.Equal(uint64(10), stats.Completed)
+	assert.GreaterOrEqual(stats.Blocked, uint64(1))
+	assert.Equal(uint64(0), stats.Pending)
+}
+
+func TestExecutorMidi(t *testing.T) {
+	assert := assert.New(t)
+
+	in := devtest.NewMockMIDIPort()
+	d := devices.NewMidiDevice(in, devtest.NewMockMIDIPort())
+	defer devtest.RunDevice(t, d.Run, d.IsConnected)()
+
+	e := devices.NewExecutor(8)
+	g := newGate()
+	var volume uint8
+	d.CC(0, 7).Bind(devices.Async(e, devices.Coalesce, func(v uint8) error {
+		g.wait()
+		volume = v
+		return nil
+	}))
+	var plays int
+	d.Note(0, 94).On.Bind(func(uint8) error { plays++; return nil })
+
+	in.SimulateReceive(midi.ControlChange(0, 7, 10))
+	<-g.started
+	for v := uint8(11); v <= 20; v++ {
+		in.SimulateReceive(midi.ControlChange(0, 7, v))
+	}
+	in.SimulateReceive(midi.NoteOn(0, 94, 127))
+	assert.Equal(1, plays, "a slow asynchronous callback must not stall other input")
+
+	close(g.open)
+	e.Wait()
+	assert.Equal(uint8(20), volume)
+	assert.Equal(uint64(2), e.Stats().Completed)
+
+	called := make(chan struct{})
+	devices.AsyncFunc(e, devices.Block, func() error { close(called); return nil })()
+	select {
+	case <-called:
+	case <-time.After(time.Second):
+		t.Fatal("AsyncFunc callback did not run")
+	}
+}
@@ -0,0 +1,373 @@
+package hui
+
+// Button is a switch with an LED, addressed by a zone and a port within it. The surface reports presses and
+// releases as a zone select followed by a port message, and the host lights the LED the same way.
+type Button struct {
+	h    *HUI
+	zone uint8
+	port uint8
+
+	On  *buttonOn
+	Off *buttonOff
+	LED *led
+}
+
+// bind runs callback for each port message addressed to this button, with whether it was a press.
+func (b *Button) bind(callback func(pressed bool) error) func() {
+	return b.h.d.CC(0, ccPortIn).Bind(func(v uint8) error {
+		if v&portMask != b.port || b.h.currentZone() != b.zone {
+			return nil
+		}
+		return callback(v&portOn != 0)
+	})
+}
+
+type buttonOn struct {
+	*Button
+}
+
+// Bind specifies the callback to run when this button is pressed.
+func (b *buttonOn) Bind(callback func() error) func() {
+	return b.bind(func(pressed bool) error {
+		if pressed {
+			return callback()
+		}
+		return nil
+	})
+}
+
+type buttonOff struct {
+	*Button
+}
+
+// Bind specifies the callback to run when this button is released.
+func (b *buttonOff) Bind(callback func() error) func() {
+	return b.bind(func(pressed bool) error {
+		if !pressed {
+			return callback()
+		}
+		return nil
+	})
+}
+
+type led struct {
+	*Button
+}
+
+// Set lights or clears this button's LED.
+func (l *led) Set(on bool) error {
+	port := l.port
+	if on {
+		port |= portOn
+	}
+	return l.h.sendZonePort(l.zone, port)
+}
+
+func (h *HUI) newButton(zone, port uint8) *Button {
+	b := &Button{
+		h:    h,
+		zone: zone,
+		port: port,
+	}
+	b.On = &buttonOn{b}
+	b.Off = &buttonOff{b}
+	b.LED = &led{b}
+	return b
+}
+
+// Zones of the standard HUI switch map. Zones 0x00-0x07 are the channel strips.
+const (
+	zoneKeyboard   uint8 = 0x08
+	zoneWindow     uint8 = 0x09
+	zonePage       uint8 = 0x0A
+	zoneAssign     uint8 = 0x0B
+	zoneAssign2    uint8 = 0x0C
+	zoneCursor     uint8 = 0x0D
+	zoneTransport  uint8 = 0x0E
+	zoneTransport2 uint8 = 0x0F
+	zoneTransport3 uint8 = 0x10
+	zoneAutoEnable uint8 = 0x17
+	zoneAutoMode   uint8 = 0x18
+	zoneStatus     uint8 = 0x19
+	zoneEdit       uint8 = 0x1A
+	zoneFunction   uint8 = 0x1B
+)
+
+// Ports within a channel strip zone.
+const (
+	portFaderTouch uint8 = 0x00
+	portSelect     uint8 = 0x01
+	portMute       uint8 = 0x02
+	portSolo       uint8 = 0x03
+	portAuto       uint8 = 0x04
+	portVSelect    uint8 = 0x05
+	portInsert     uint8 = 0x06
+	portRecReady   uint8 = 0x07
+)
+
+type Keyboard struct {
+	CTRL_CLUTCH *Button
+	SHIFT_ADD   *Button
+	EDIT_MODE   *Button
+	UNDO        *Button
+	ALT_FINE    *Button
+	OPTION_ALL  *Button
+	EDIT_TOOL   *Button
+	SAVE        *Button
+}
+
+func (h *HUI) newKeyboard() *Keyboard {
+	return &Keyboard{
+		CTRL_CLUTCH: h.newButton(zoneKeyboard, 0),
+		SHIFT_ADD:   h.newButton(zoneKeyboard, 1),
+		EDIT_MODE:   h.newButton(zoneKeyboard, 2),
+		UNDO:        h.newButton(zoneKeyboard, 3),
+		ALT_FINE:    h.newButton(zoneKeyboard, 4),
+		OPTION_ALL:  h.newButton(zoneKeyboard, 5),
+		EDIT_TOOL:   h.newButton(zoneKeyboard, 6),
+		SAVE:        h.newButton(zoneKeyboard, 7),
+	}
+}
+
+type Window struct {
+	MIX       *Button
+	EDIT      *Button
+	TRANSPORT *Button
+	MEM_LOC   *Button
+	STATUS    *Button
+	ALT       *Button
+}
+
+func (h *HUI) newWindow() *Window {
+	return &Window{
+		MIX:       h.newButton(zoneWindow, 0),
+		EDIT:      h.newButton(zoneWindow, 1),
+		TRANSPORT: h.newButton(zoneWindow, 2),
+		MEM_LOC:   h.newButton(zoneWindow, 3),
+		STATUS:    h.newButton(zoneWindow, 4),
+		ALT:       h.newButton(zoneWindow, 5),
+	}
+}
+
+type Page struct {
+	CHANNEL_L *Button
+	BANK_L    *Button
+	CHANNEL_R *Button
+	BANK_R    *Button
+}
+
+func (h *HUI) newPage() *Page {
+	return &Page{
+		CHANNEL_L: h.newButton(zonePage, 0),
+		BANK_L:    h.newButton(zonePage, 1),
+		CHANNEL_R: h.newButton(zonePage, 2),
+		BANK_R:    h.newButton(zonePage, 3),
+	}
+}
+
+type Assign struct {
+	OUTPUT *Button
+	INPUT  *Button
+	PAN    *Button
+	SEND_E *Button
+	SEND_D *Button
+	SEND_C *Button
+	SEND_B *Button
+	SEND_A *Button
+
+	ASSIGN        *Button
+	DEFAULT       *Button
+	SUSPEND       *Button
+	SHIFT         *Button
+	MUTE          *Button
+	BYPASS        *Button
+	REC_READY_ALL *Button
+}
+
+func (h *HUI) newAssign() *Assign {
+	return &Assign{
+		OUTPUT:        h.newButton(zoneAssign, 0),
+		INPUT:         h.newButton(zoneAssign, 1),
+		PAN:           h.newButton(zoneAssign, 2),
+		SEND_E:        h.newButton(zoneAssign, 3),
+		SEND_D:        h.newButton(zoneAssign, 4),
+		SEND_C:        h.newButton(zoneAssign, 5),
+		SEND_B:        h.newButton(zoneAssign, 6),
+		SEND_A:        h.newButton(zoneAssign, 7),
+		ASSIGN:        h.newButton(zoneAssign2, 0),
+		DEFAULT:       h.newButton(zoneAssign2, 1),
+		SUSPEND:       h.newButton(zoneAssign2, 2),
+		SHIFT:         h.newButton(zoneAssign2, 3),
+		MUTE:          h.newButton(zoneAssign2, 4),
+		BYPASS:        h.newButton(zoneAssign2, 5),
+		REC_READY_ALL: h.newButton(zoneAssign2, 6),
+	}
+}
+
+type Cursor struct {
+	DOWN    *Button
+	LEFT    *Button
+	MODE    *Button
+	RIGHT   *Button
+	UP      *Button
+	SCRUB   *Button
+	SHUTTLE *Button
+}
+
+func (h *HUI) newCursor() *Cursor {
+	return &Cursor{
+		DOWN:    h.newButton(zoneCursor, 0),
+		LEFT:    h.newButton(zoneCursor, 1),
+		MODE:    h.newButton(zoneCursor, 2),
+		RIGHT:   h.newButton(zoneCursor, 3),
+		UP:      h.newButton(zoneCursor, 4),
+		SCRUB:   h.newButton(zoneCursor, 5),
+		SHUTTLE: h.newButton(zoneCursor, 6),
+	}
+}
+
+type Transport struct {
+	TALKBACK *Button
+	REWIND   *Button
+	FAST_FWD *Button
+	STOP     *Button
+	PLAY     *Button
+	RECORD   *Button
+
+	RTZ         *Button
+	END         *Button
+	ONLINE      *Button
+	LOOP        *Button
+	QUICK_PUNCH *Button
+
+	AUDITION *Button
+	PRE      *Button
+	IN       *Button
+	OUT      *Button
+	POST     *Button
+}
+
+func (h *HUI) newTransport() *Transport {
+	return &Transport{
+		TALKBACK:    h.newButton(zoneTransport, 0),
+		REWIND:      h.newButton(zoneTransport, 1),
+		FAST_FWD:    h.newButton(zoneTransport, 2),
+		STOP:        h.newButton(zoneTransport, 3),
+		PLAY:        h.newButton(zoneTransport, 4),
+		RECORD:      h.newButton(zoneTransport, 5),
+		RTZ:         h.newButton(zoneTransport2, 0),
+		END:         h.newButton(zoneTransport2, 1),
+		ONLINE:      h.newButton(zoneTransport2, 2),
+		LOOP:        h.newButton(zoneTransport2, 3),
+		QUICK_PUNCH: h.newButton(zoneTransport2, 4),
+		AUDITION:    h.newButton(zoneTransport3, 0),
+		PRE:         h.newButton(zoneTransport3, 1),
+		IN:          h.newButton(zoneTransport3, 2),
+		OUT:         h.newButton(zoneTransport3, 3),
+		POST:        h.newButton(zoneTransport3, 4),
+	}
+}
+
+type AutoEnable struct {
+	PLUGIN    *Button
+	PAN       *Button
+	FADER     *Button
+	SEND_MUTE *Button
+	SEND      *Button
+	MUTE      *Button
+}
+
+func (h *HUI) newAutoEnable() *AutoEnable {
+	return &AutoEnable{
+		PLUGIN:    h.newButton(zoneAutoEnable, 0),
+		PAN:       h.newButton(zoneAutoEnable, 1),
+		FADER:     h.newButton(zoneAutoEnable, 2),
+		SEND_MUTE: h.newButton(zoneAutoEnable, 3),
+		SEND:      h.newButton(zoneAutoEnable, 4),
+		MUTE:      h.newButton(zoneAutoEnable, 5),
+	}
+}
+
+type AutoMode struct {
+	TRIM  *Button
+	LATCH *Button
+	READ  *Button
+	OFF   *Button
+	WRITE *Button
+	TOUCH *Button
+}
+
+func (h *HUI) newAutoMode() *AutoMode {
+	return &AutoMode{
+		TRIM:  h.newButton(zoneAutoMode, 0),
+		LATCH: h.newButton(zoneAutoMode, 1),
+		READ:  h.newButton(zoneAutoMode, 2),
+		OFF:   h.newButton(zoneAutoMode, 3),
+		WRITE: h.newButton(zoneAutoMode, 4),
+		TOUCH: h.newButton(zoneAutoMode, 5),
+	}
+}
+
+type Status struct {
+	PHASE   *Button
+	MONITOR *Button
+	AUTO    *Button
+	SUSPEND *Button
+	CREATE  *Button
+	GROUP   *Button
+}
+
+func (h *HUI) newStatus() *Status {
+	return &Status{
+		PHASE:   h.newButton(zoneStatus, 0),
+		MONITOR: h.newButton(zoneStatus, 1),
+		AUTO:    h.newButton(zoneStatus, 2),
+		SUSPEND: h.newButton(zoneStatus, 3),
+		CREATE:  h.newButton(zoneStatus, 4),
+		GROUP:   h.newButton(zoneStatus, 5),
+	}
+}
+
+type Edit struct {
+	PASTE    *Button
+	CUT      *Button
+	CAPTURE  *Button
+	DELETE   *Button
+	COPY     *Button
+	SEPARATE *Button
+}
+
+func (h *HUI) newEdit() *Edit {
+	return &Edit{
+		PASTE:    h.newButton(zoneEdit, 0),
+		CUT:      h.newButton(zoneEdit, 1),
+		CAPTURE:  h.newButton(zoneEdit, 2),
+		DELETE:   h.newButton(zoneEdit, 3),
+		COPY:     h.newButton(zoneEdit, 4),
+		SEPARATE: h.newButton(zoneEdit, 5),
+	}
+}
+
+type Function struct {
+	F1  *Button
+	F2  *Button
+	F3  *Button
+	F4  *Button
+	F5  *Button
+	F6  *Button
+	F7  *Button
+	ESC *Button
+}
+
+func (h *HUI) newFunction() *Function {
+	return &Function{
+		F1:  h.newButton(zoneFunction, 0),
+		F2:  h.newButton(zoneFunction, 1),
+		F3:  h.newButton(zoneFunction, 2),
+		F4:  h.newButton(zoneFunction, 3),
+		F5:  h.newButton(zoneFunction, 4),
+		F6:  h.newButton(zoneFunction, 5),
+		F7:  h.newButton(zoneFunction, 6),
+		ESC: h.newButton(zoneFunction, 7),
+	}
+}
@@ -0,0 +1,137 @@
+package hui
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	// ScribbleWidth is the number of characters on each scribble strip.
+	ScribbleWidth = 4
+
+	// MainWidth is the number of characters on each line of the main display.
+	MainWidth = 40
+	// MainLines is the number of lines on the main display.
+	MainLines = 2
+	// mainZoneWidth is the number of characters written by a single main display message.
+	mainZoneWidth = 10
+
+	scribbleAssign uint8 = 0x08 // scribble strip index of the select-assign display
+)
+
+// Scribble is a 4-character display above a channel strip or in the select-assign section.
+type Scribble struct {
+	h     *HUI
+	index uint8
+
+	mu   sync.Mutex
+	text string
+}
+
+func (h *HUI) newScribble(index uint8) *Scribble {
+	return &Scribble{h: h, index: index, text: strings.Repeat(" ", ScribbleWidth)}
+}
+
+// Set writes text to the scribble strip. Text is truncated or padded with spaces to ScribbleWidth characters.
+func (s *Scribble) Set(text string) error {
+	b := displayBytes(text, ScribbleWidth)
+	s.mu.Lock()
+	s.text = string(b)
+	s.mu.Unlock()
+	return s.h.sendSysEx(sysExScribble, append([]byte{s.index}, b...)...)
+}
+
+// Text returns the current contents of the scribble strip.
+func (s *Scribble) Text() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.text
+}
+
+// MainDisplay is the 2x40 character display in the center section. The surface updates it in zones of ten
+// characters, four to a line.
+type MainDisplay struct {
+	h *HUI
+
+	mu  sync.Mutex
+	buf [MainWidth * MainLines]byte
+}
+
+func (h *HUI) newMainDisplay() *MainDisplay {
+	m := &MainDisplay{h: h}
+	for i := range m.buf {
+		m.buf[i] = ' '
+	}
+	return m
+}
+
+// Set writes text starting at the given character offset, where 0 is the start of the top line and MainWidth is
+// the start of the bottom line. Text running past the end of the display is truncated. Every zone the text
+// touches is resent in full.
+func (m *MainDisplay) Set(offset int, text string) error {
+	if offset < 0 || offset >= len(m.buf) {
+		return fmt.Errorf("invalid display offset %d: must be between 0 and %d", offset, len(m.buf)-1)
+	}
+	b := displayBytes(text, -1)
+	if len(b) > len(m.buf)-offset {
+		b = b[:len(m.buf)-offset]
+	}
+	if len(b) == 0 {
+		return nil
+	}
+
+	m.mu.Lock()
+	copy(m.buf[offset:], b)
+	first, last := offset/mainZoneWidth, (offset+len(b)-1)/mainZoneWidth
+	zones := make([][]byte, 0, last-first+1)
+	for z := first; z <= last; z++ {
+		msg := append([]byte{uint8(z)}, m.buf[z*mainZoneWidth:(z+1)*mainZoneWidth]...)
+		zones = append(zones, msg)
+	}
+	m.mu.Unlock()
+
+	for _, msg := range zones {
+		if err := m.h.sendSysEx(sysExMain, msg...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetLine replaces the given line (0 for top, 1 for bottom), padding text with spaces to MainWidth characters.
+func (m *MainDisplay) SetLine(line int, text string) error {
+	if line < 0 || line >= MainLines {
+		return fmt.Errorf("invalid display line %d: must be 0 or 1", line)
+	}
+	return m.Set(line*MainWidth, string(displayBytes(text, MainWidth)))
+}
+
+// Lines returns the current contents of the display as written by Set.
+func (m *MainDisplay) Lines() (top, bottom string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return string(m.buf[:MainWidth]), string(m.buf[MainWidth:])
+}
+
+// displayBytes converts text to the display's 7-bit character set, replacing anything unprintable with a space.
+// If width is not negative, the result is truncated or padded with spaces to exactly width characters.
+func displayBytes(text string, width int) []byte {
+	b := make([]byte, 0, len(text))
+	for _, r := range text {
+		if r < 0x20 || r > 0x7E {
+			r = ' '
+		}
+		b = append(b, byte(r))
+	}
+	if width < 0 {
+		return b
+	}
+	if len(b) > width {
+		return b[:width]
+	}
+	for len(b) < width {
+		b = append(b, ' ')
+	}
+	return b
+}
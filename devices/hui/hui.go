@@ -0,0 +1,201 @@
+// Package hui implements the Mackie HUI protocol used by legacy Pro Tools style control surfaces.
+//
+// arpad plays the host side: it keeps the surface online with pings, receives switch presses, fader moves and
+// V-Pot turns, and drives LEDs, motor faders, V-Pot rings, meters and displays.
+//
+// Unlike MCU, HUI does not give each switch its own note. Switches are grouped into zones of up to eight ports;
+// every press, release or LED change is a zone select followed by a port message.
+package hui
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	dev "github.com/jdginn/arpad/devices"
+	"github.com/jdginn/arpad/logging"
+
+	midi "gitlab.com/gomidi/midi/v2"
+)
+
+var log *slog.Logger
+
+func init() {
+	log = logging.Get(logging.MIDI_IN)
+}
+
+const (
+	// NumStrips is the number of channel strips on a HUI surface.
+	NumStrips = 8
+
+	// The host must ping at least once per second or the surface drops offline.
+	pingInterval    = 1 * time.Second
+	responseTimeout = 3 * time.Second
+
+	notePing        uint8 = 0x00
+	pingReply       uint8 = 0x7F
+	ccZoneSelectIn  uint8 = 0x0F // surface -> host: zone for the following port message
+	ccPortIn        uint8 = 0x2F // surface -> host: port in bits 0-2, bit 6 set when pressed
+	ccZoneSelectOut uint8 = 0x0C // host -> surface: zone for the following port message
+	ccPortOut       uint8 = 0x2C // host -> surface: port in bits 0-2, bit 6 set to light the LED
+	portOn          uint8 = 0x40
+	portMask        uint8 = 0x07
+)
+
+// SysEx commands understood by HUI surfaces.
+const (
+	sysExScribble uint8 = 0x10
+	sysExMain     uint8 = 0x12
+)
+
+// HUI represents a HUI control surface.
+type HUI struct {
+	d *dev.MidiDevice
+
+	// mu guards the zone most recently selected by the surface and the ping state.
+	mu       sync.Mutex
+	zoneIn   uint8
+	lastPing time.Time
+	online   bool
+
+	// outMu keeps outgoing zone/port pairs from interleaving.
+	outMu sync.Mutex
+
+	Strips []*Strip
+	Main   *MainDisplay
+	Jog    *VPot
+
+	// AssignScribble is the 4-character display in the select-assign section.
+	AssignScribble *Scribble
+
+	Keyboard   *Keyboard
+	Window     *Window
+	Page       *Page
+	Assign     *Assign
+	Cursor     *Cursor
+	Transport  *Transport
+	AutoEnable *AutoEnable
+	AutoMode   *AutoMode
+	Status     *Status
+	Edit       *Edit
+	Function   *Function
+}
+
+// New returns a properly initialized HUI.
+func New(d *dev.MidiDevice) *HUI {
+	h := &HUI{d: d}
+	d.CC(0, ccZoneSelectIn).Bind(func(zone uint8) error {
+		h.mu.Lock()
+		h.zoneIn = zone
+		h.mu.Unlock()
+		return nil
+	})
+	d.Note(0, notePing).On.Bind(func(v uint8) error {
+		if v != pingReply {
+			return nil
+		}
+		h.mu.Lock()
+		if !h.online {
+			log.Info("HUI surface online")
+		}
+		h.lastPing = time.Now()
+		h.online = true
+		h.mu.Unlock()
+		return nil
+	})
+
+	for i := uint8(0); i < NumStrips; i++ {
+		h.Strips = append(h.Strips, h.newStrip(i))
+	}
+	h.Main = h.newMainDisplay()
+	h.Jog = h.newVPot(ccJog)
+	h.AssignScribble = h.newScribble(scribbleAssign)
+	h.Keyboard = h.newKeyboard()
+	h.Window = h.newWindow()
+	h.Page = h.newPage()
+	h.Assign = h.newAssign()
+	h.Cursor = h.newCursor()
+	h.Transport = h.newTransport()
+	h.AutoEnable = h.newAutoEnable()
+	h.AutoMode = h.newAutoMode()
+	h.Status = h.newStatus()
+	h.Edit = h.newEdit()
+	h.Function = h.newFunction()
+	return h
+}
+
+// Online reports whether the surface has answered a ping recently.
+func (h *HUI) Online() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.online
+}
+
+// Run pings the surface to keep it online and runs the underlying MIDI device until ctx is cancelled. Pinging
+// stops before Run returns, including when the MIDI device fails to start.
+func (h *HUI) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.keepAlive(ctx)
+	}()
+	err := h.d.Run(ctx)
+	cancel()
+	<-done
+	return err
+}
+
+func (h *HUI) keepAlive(ctx context.Context) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.ping()
+		}
+	}
+}
+
+// ping sends a single keep-alive and marks the surface offline if it has stopped answering.
+func (h *HUI) ping() {
+	if err := h.d.Note(0, notePing).On.Set(0x00); err != nil {
+		log.Debug("failed to ping HUI surface", slog.Any("err", err))
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.online && time.Since(h.lastPing) > responseTimeout {
+		log.Warn("HUI surface stopped answering pings")
+		h.online = false
+	}
+}
+
+// currentZone returns the zone most recently selected by the surface.
+func (h *HUI) currentZone() uint8 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.zoneIn
+}
+
+// sendZonePort selects zone on the surface and then sends port to it.
+func (h *HUI) sendZonePort(zone, port uint8) error {
+	h.outMu.Lock()
+	defer h.outMu.Unlock()
+	if err := h.d.CC(0, ccZoneSelectOut).Set(zone); err != nil {
+		return err
+	}
+	return h.d.CC(0, ccPortOut).Set(port)
+}
+
+func (h *HUI) sysExHeader() []byte {
+	return []byte{0x00, 0x00, 0x66, 0x05, 0x00}
+}
+
+func (h *HUI) sendSysEx(command uint8, data ...byte) error {
+	b := append(h.sysExHeader(), command)
+	b = append(b, data...)
+	return h.d.SysEx.Set(midi.SysEx(b))
+}
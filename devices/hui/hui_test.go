@@ -0,0 +1,155 @@
+package hui
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	midi "gitlab.com/gomidi/midi/v2"
+
+	dev "github.com/jdginn/arpad/devices"
+	devtest "github.com/jdginn/arpad/devices/devicestesting"
+)
+
+func TestSwitches(t *testing.T) {
+	assert := assert.New(t)
+
+	in := devtest.NewMockMIDIPort()
+	out := devtest.NewMockMIDIPort()
+	h := New(dev.NewMidiDevice(in, out))
+	defer devtest.RunDevice(t, h.Run, h.d.IsConnected)()
+
+	var presses, releases, stops, mutes int
+	h.Transport.PLAY.On.Bind(func() error { presses++; return nil })
+	h.Transport.PLAY.Off.Bind(func() error { releases++; return nil })
+	h.Transport.STOP.On.Bind(func() error { stops++; return nil })
+	h.Strips[4].Mute.On.Bind(func() error { mutes++; return nil })
+
+	in.SimulateReceive(midi.ControlChange(0, 0x0F, 0x0E))
+	in.SimulateReceive(midi.ControlChange(0, 0x2F, 0x44))
+	in.SimulateReceive(midi.ControlChange(0, 0x0F, 0x0E))
+	in.SimulateReceive(midi.ControlChange(0, 0x2F, 0x04))
+	in.SimulateReceive(midi.ControlChange(0, 0x0F, 0x04))
+	in.SimulateReceive(midi.ControlChange(0, 0x2F, 0x42))
+
+	assert.Equal(1, presses)
+	assert.Equal(1, releases)
+	assert.Equal(0, stops, "other ports in the zone must not fire")
+	assert.Equal(1, mutes)
+
+	assert.NoError(h.Transport.PLAY.LED.Set(true))
+	assert.NoError(h.Strips[4].Mute.LED.Set(false))
+	assert.Equal([]midi.Message{
+		midi.ControlChange(0, 0x0C, 0x0E),
+		midi.ControlChange(0, 0x2C, 0x44),
+		midi.ControlChange(0, 0x0C, 0x04),
+		midi.ControlChange(0, 0x2C, 0x02),
+	}, out.GetSentMessages())
+}
+
+func TestFaderTouch(t *testing.T) {
+	assert := assert.New(t)
+
+	in := devtest.NewMockMIDIPort()
+	out := devtest.NewMockMIDIPort()
+	h := New(dev.NewMidiDevice(in, out))
+	defer devtest.RunDevice(t, h.Run, h.d.IsConnected)()
+
+	var pos uint16
+	h.Strips[2].Fader.Bind(func(v uint16) error { pos = v; return nil })
+
+	in.SimulateReceive(midi.ControlChange(0, 0x0F, 0x02))
+	in.SimulateReceive(midi.ControlChange(0, 0x2F, 0x40))
+	in.SimulateReceive(midi.ControlChange(0, 0x02, 0x10))
+	in.SimulateReceive(midi.ControlChange(0, 0x22, 0x01))
+	assert.True(h.Strips[2].Fader.Touched())
+	assert.Equal(uint16(0x10<<7|0x01), pos)
+
+	assert.NoError(h.Strips[2].Fader.Set(0x1000))
+	assert.NoError(h.Strips[2].Fader.Set(0x2000))
+	assert.Empty(out.GetSentMessages(), "touched faders must not be moved")
+
+	in.SimulateReceive(midi.ControlChange(0, 0x0F, 0x02))
+	in.SimulateReceive(midi.ControlChange(0, 0x2F, 0x00))
+	assert.False(h.Strips[2].Fader.Touched())
+	assert.Equal([]midi.Message{
+		midi.ControlChange(0, 0x02, 0x40),
+		midi.ControlChange(0, 0x22, 0x00),
+	}, out.GetSentMessages(), "the last deferred move should be sent on release")
+
+	assert.Error(h.Strips[2].Fader.Set(FaderMax + 1))
+}
+
+func TestDisplays(t *testing.T) {
+	assert := assert.New(t)
+
+	out := devtest.NewMockMIDIPort()
+	h := New(dev.NewMidiDevice(devtest.NewMockMIDIPort(), out))
+
+	assert.NoError(h.Strips[1].Scribble.Set("Vocals"))
+	assert.NoError(h.AssignScribble.Set("Pn"))
+	assert.Equal([]byte{0xF0, 0x00, 0x00, 0x66, 0x05, 0x00, 0x10, 0x01, 'V', 'o', 'c', 'a', 0xF7},
+		out.GetSentMessages()[0].Bytes())
+	assert.Equal([]byte{0xF0, 0x00, 0x00, 0x66, 0x05, 0x00, 0x10, 0x08, 'P', 'n', ' ', ' ', 0xF7},
+		out.GetSentMessages()[1].Bytes())
+	assert.Equal("Voca", h.Strips[1].Scribble.Text())
+
+	assert.NoError(h.Main.Set(48, "Bus 1"))
+	sent := out.GetSentMessages()[2:]
+	assert.Len(sent, 2, "text spanning two zones should resend both")
+	assert.Equal([]byte{0xF0, 0x00, 0x00, 0x66, 0x05, 0x00, 0x12, 0x04,
+		' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', 'B', 'u', 0xF7}, sent[0].Bytes())
+	_, bottom := h.Main.Lines()
+	assert.Equal("        Bus 1  ", bottom[:15])
+
+	assert.Error(h.Main.Set(80, "x"))
+	assert.Error(h.Main.SetLine(2, "x"))
+}
+
+func TestPing(t *testing.T) {
+	assert := assert.New(t)
+
+	in := devtest.NewMockMIDIPort()
+	out := devtest.NewMockMIDIPort()
+	h := New(dev.NewMidiDevice(in, out))
+	defer devtest.RunDevice(t, h.Run, h.d.IsConnected)()
+
+	assert.False(h.Online())
+	h.ping()
+	assert.Equal([]midi.Message{midi.NoteOn(0, 0x00, 0x00)}, out.GetSentMessages())
+
+	in.SimulateReceive(midi.NoteOn(0, 0x00, 0x7F))
+	assert.True(h.Online())
+
+	h.mu.Lock()
+	h.lastPing = time.Now().Add(-2 * responseTimeout)
+	h.mu.Unlock()
+	h.ping()
+	assert.False(h.Online())
+}
+
+func TestRunKeepAlive(t *testing.T) {
+	assert := assert.New(t)
+
+	out := devtest.NewMockMIDIPort()
+	h := New(dev.NewMidiDevice(devtest.NewMockMIDIPort(), out))
+	stop := devtest.RunDevice(t, h.Run, h.d.IsConnected)
+	assert.Eventually(func() bool { return len(out.GetSentMessages()) > 0 }, 3*pingInterval, 10*time.Millisecond,
+		"Run should ping the surface")
+	stop()
+	assert.Equal(midi.NoteOn(0, 0x00, 0x00), out.GetSentMessages()[0])
+
+	// A device that fails to open must not leave the pinger running.
+	out = devtest.NewMockMIDIPort()
+	out.SetOpenError(errors.New("no such device"))
+	h = New(dev.NewMidiDevice(devtest.NewMockMIDIPort(), out))
+	assert.Error(h.Run(context.Background()))
+}
+
+func TestRelative(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(3, DecodeRelative(0x43))
+	assert.Equal(-5, DecodeRelative(0x05))
+}
@@ -0,0 +1,204 @@
+package hui
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+const (
+	ccVPot     uint8 = 0x40 // V-Pot rotation, CC 64-71
+	ccVPotRing uint8 = 0x10 // V-Pot LED ring, CC 16-23
+	ccJog      uint8 = 0x0D // Jog wheel rotation
+
+	// FaderMax is the highest fader position. Faders send and receive a 14-bit MSB/LSB pair on CC n and n+32.
+	FaderMax uint16 = 0x3FFF
+
+	meterMaxLevel uint8 = 0x0C
+)
+
+// Strip is a single HUI channel strip. Each strip is its own zone, numbered after the strip.
+type Strip struct {
+	Fader    *Fader
+	VPot     *VPot
+	Ring     *Ring
+	Meter    *Meter
+	Scribble *Scribble
+
+	Select   *Button
+	Mute     *Button
+	Solo     *Button
+	Auto     *Button
+	VSelect  *Button
+	Insert   *Button
+	RecReady *Button
+}
+
+func (h *HUI) newStrip(id uint8) *Strip {
+	return &Strip{
+		Fader:    h.newFader(id),
+		VPot:     h.newVPot(ccVPot + id),
+		Ring:     &Ring{h: h, cc: ccVPotRing + id},
+		Meter:    &Meter{h: h, strip: id},
+		Scribble: h.newScribble(id),
+		Select:   h.newButton(id, portSelect),
+		Mute:     h.newButton(id, portMute),
+		Solo:     h.newButton(id, portSolo),
+		Auto:     h.newButton(id, portAuto),
+		VSelect:  h.newButton(id, portVSelect),
+		Insert:   h.newButton(id, portInsert),
+		RecReady: h.newButton(id, portRecReady),
+	}
+}
+
+// Fader is a touch-sensitive motorized fader.
+//
+// HUI surfaces report a touch before any movement and a release after the last one, and expect the host to
+// leave the motor alone in between. Set calls made while the fader is touched are therefore held back, and the
+// most recent one is sent once the fader is released so the motor settles where the host wants it.
+type Fader struct {
+	h     *HUI
+	strip uint8
+
+	// Touch is pressed when a finger lands on the fader and released when it lifts.
+	Touch *Button
+
+	mu      sync.Mutex
+	touched bool
+	pending bool
+	pos     uint16
+}
+
+func (h *HUI) newFader(strip uint8) *Fader {
+	f := &Fader{
+		h:     h,
+		strip: strip,
+		Touch: h.newButton(strip, portFaderTouch),
+	}
+	f.Touch.bind(f.setTouched)
+	return f
+}
+
+func (f *Fader) setTouched(touched bool) error {
+	f.mu.Lock()
+	f.touched = touched
+	send := !touched && f.pending
+	f.pending = false
+	pos := f.pos
+	f.mu.Unlock()
+	if send {
+		return f.h.d.CC14(0, f.strip).Set(pos)
+	}
+	return nil
+}
+
+// Touched reports whether a finger is currently on the fader.
+func (f *Fader) Touched() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.touched
+}
+
+// Bind specifies the callback to run when the fader is moved.
+func (f *Fader) Bind(callback func(uint16) error) func() {
+	return f.h.d.CC14(0, f.strip).Bind(callback)
+}
+
+// Set moves the motorized fader. If the fader is being touched, the move is deferred until it is released.
+func (f *Fader) Set(val uint16) error {
+	if val > FaderMax {
+		return fmt.Errorf("invalid fader position %d: must be at most %d", val, FaderMax)
+	}
+	f.mu.Lock()
+	f.pos = val
+	if f.touched {
+		f.pending = true
+		f.mu.Unlock()
+		return nil
+	}
+	f.mu.Unlock()
+	return f.h.d.CC14(0, f.strip).Set(val)
+}
+
+// VPot is a relative rotary encoder. Each message carries a direction in bit 6 (set for clockwise) and a tick
+// count in bits 0-5.
+type VPot struct {
+	h  *HUI
+	cc uint8
+}
+
+func (h *HUI) newVPot(cc uint8) *VPot {
+	return &VPot{h: h, cc: cc}
+}
+
+// DecodeRelative converts a HUI relative encoder value into a signed number of ticks.
+func DecodeRelative(v uint8) int {
+	ticks := int(v & 0x3F)
+	if v&0x40 == 0 {
+		return -ticks
+	}
+	return ticks
+}
+
+// Bind specifies the callback to run with the signed number of ticks each time the V-Pot is turned.
+func (e *VPot) Bind(callback func(int) error) func() {
+	return e.h.d.CC(0, e.cc).Bind(func(v uint8) error {
+		return callback(DecodeRelative(v))
+	})
+}
+
+// RingMode selects how the V-Pot LED ring renders its position.
+type RingMode uint8
+
+const (
+	RingDot      RingMode = 0 // a single LED at the position
+	RingBoostCut RingMode = 1 // LEDs from the center to the position
+	RingWrap     RingMode = 2 // LEDs from the left to the position
+	RingSpread   RingMode = 3 // LEDs spreading symmetrically from the center
+)
+
+// Ring is the 11-segment LED ring around a V-Pot, plus the center LED below it.
+type Ring struct {
+	h  *HUI
+	cc uint8
+}
+
+// Set lights the ring. pos ranges from 0 (all off) to 11; center lights the LED below the ring.
+func (r *Ring) Set(mode RingMode, pos uint8, center bool) error {
+	if pos > 11 {
+		return fmt.Errorf("invalid ring position %d: must be between 0 and 11", pos)
+	}
+	v := uint8(mode&0x03)<<4 | pos
+	if center {
+		v |= 0x40
+	}
+	return r.h.d.CC(0, r.cc).Set(v)
+}
+
+// SetNorm lights the ring to show v in [0.0, 1.0] using the given mode.
+func (r *Ring) SetNorm(mode RingMode, v float64) error {
+	v = math.Max(0, math.Min(1, v))
+	return r.Set(mode, 1+uint8(math.Round(v*10)), false)
+}
+
+// Side selects one half of a stereo meter.
+type Side uint8
+
+const (
+	Left  Side = 0
+	Right Side = 1
+)
+
+// Meter is a strip's stereo signal level meter. Levels are sent as polyphonic aftertouch keyed by strip, with
+// the side in the high nibble and the level in the low nibble.
+type Meter struct {
+	h     *HUI
+	strip uint8
+}
+
+// Set shows level in [0.0, 1.0] on one side of the meter.
+func (m *Meter) Set(side Side, level float64) error {
+	level = math.Max(0, math.Min(1, level))
+	v := uint8(side&0x01)<<4 | uint8(math.Round(level*float64(meterMaxLevel)))
+	return m.h.d.PolyAftertouch(0, m.strip).Set(v)
+}
@@ -0,0 +1,155 @@
+package devices
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Limiter caps the rate at which values are forwarded to an endpoint's Set method, merging values that arrive too
+// quickly into the most recent one.
+//
+// The first value after a quiet period is sent straight away. Values arriving within the interval that follows
+// replace each other and the latest is sent when the interval ends, so the final resting value always reaches the
+// endpoint. Sends never overlap: a value arriving while a slow send is in progress waits for it to finish.
+//
+// Use one Limiter per endpoint, e.g. to forward fader moves to a DAW at 60 Hz:
+//
+//	volume := devices.NewLimiter(60, track.Volume.Set)
+//	fader.Bind(func(v float64) error { return volume.Set(v) })
+type Limiter[T any] struct {
+	set      func(T) error
+	interval time.Duration
+
+	mu         sync.Mutex
+	pending    T
+	hasPending bool
+	inFlight   bool
+	last       time.Time
+	timer      *time.Timer
+	closed     bool
+
+	sent, merged atomic.Uint64
+}
+
+// NewLimiter returns a Limiter that forwards values to set at most rate times per second. A rate of zero or less
+// disables limiting but still keeps sends from overlapping.
+func NewLimiter[T any](rate float64, set func(T) error) *Limiter[T] {
+	var interval time.Duration
+	if rate > 0 {
+		interval = time.Duration(float64(time.Second) / rate)
+	}
+	return &Limiter[T]{set: set, interval: interval}
+}
+
+// Set forwards value now if the endpoint is due for an update and otherwise holds it until the end of the current
+// interval, replacing any value already held. Only errors from sends made during the call are returned; errors
+// from deferred sends are logged.
+func (l *Limiter[T]) Set(value T) error {
+	l.mu.Lock()
+	if l.inFlight || l.timer != nil || time.Since(l.last) < l.interval {
+		if l.hasPending {
+			l.merged.Add(1)
+		}
+		l.pending, l.hasPending = value, true
+		l.schedule()
+		l.mu.Unlock()
+		return nil
+	}
+	l.inFlight = true
+	l.last = time.Now()
+	l.mu.Unlock()
+
+	return l.send(value)
+}
+
+// Flush sends the held value, if any, without waiting for the current interval to end. If a send is already in
+// progress the held value is left for it to pick up.
+func (l *Limiter[T]) Flush() error {
+	l.mu.Lock()
+	if l.inFlight || !l.hasPending {
+		l.mu.Unlock()
+		return nil
+	}
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	value := l.take()
+	l.mu.Unlock()
+
+	return l.send(value)
+}
+
+// Close flushes the held value and stops the limiter from scheduling further sends. Later calls to Set are still
+// forwarded, without a rate limit on deferred values.
+func (l *Limiter[T]) Close() error {
+	err := l.Flush()
+	l.mu.Lock()
+	l.closed = true
+	l.mu.Unlock()
+	return err
+}
+
+// Sent returns the number of values sent to the endpoint.
+func (l *Limiter[T]) Sent() uint64 {
+	return l.sent.Load()
+}
+
+// Merged returns the number of values replaced by a newer one before they could be sent.
+func (l *Limiter[T]) Merged() uint64 {
+	return l.merged.Load()
+}
+
+// take removes the held value and marks a send in progress. The caller must hold l.mu.
+func (l *Limiter[T]) take() T {
+	value := l.pending
+	var zero T
+	l.pending, l.hasPending = zero, false
+	l.inFlight = true
+	l.last = time.Now()
+	return value
+}
+
+// schedule arranges for the held value to be sent when the current interval ends. The caller must hold l.mu.
+func (l *Limiter[T]) schedule() {
+	if l.timer != nil || l.inFlight {
+		// The pending timer or the send in progress will pick up the value.
+		return
+	}
+	wait := time.Until(l.last.Add(l.interval))
+	if l.closed {
+		wait = 0
+	}
+	l.timer = time.AfterFunc(wait, l.flushDeferred)
+}
+
+func (l *Limiter[T]) flushDeferred() {
+	l.mu.Lock()
+	l.timer = nil
+	if l.inFlight || !l.hasPending {
+		l.mu.Unlock()
+		return
+	}
+	value := l.take()
+	l.mu.Unlock()
+
+	if err := l.send(value); err != nil {
+		execLog.Error("failed to send rate-limited value", slog.Any("value", value), slog.Any("err", err))
+	}
+}
+
+// send forwards value to the endpoint and then schedules any value that arrived meanwhile.
+func (l *Limiter[T]) send(value T) error {
+	err := l.set(value)
+	l.sent.Add(1)
+
+	l.mu.Lock()
+	l.inFlight = false
+	if l.hasPending {
+		l.schedule()
+	}
+	l.mu.Unlock()
+	return err
+}
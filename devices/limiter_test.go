@@ -0,0 +1,95 @@
+package devices_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jdginn/arpad/devices"
+)
+
+func TestLimiter(t *testing.T) {
+	assert := assert.New(t)
+
+	var mu sync.Mutex
+	var sent []int
+	last := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return sent[len(sent)-1]
+	}
+	l := devices.NewLimiter(20, func(v int) error {
+		mu.Lock()
+		sent = append(sent, v)
+		mu.Unlock()
+		return nil
+	})
+
+	start := time.Now()
+	for v := 0; v < 100; v++ {
+		assert.NoError(l.Set(v))
+	}
+	assert.Equal(uint64(1), l.Sent(), "the first value should be sent immediately")
+	assert.Eventually(func() bool { return l.Sent() == 2 }, time.Second, time.Millisecond)
+	assert.GreaterOrEqual(time.Since(start), 40*time.Millisecond, "deferred values must wait for the interval")
+	assert.Equal(99, last(), "the final resting value must be sent")
+	assert.Equal(uint64(98), l.Merged())
+
+	// A steady stream is sent at the target rate.
+	start = time.Now()
+	for time.Since(start) < 220*time.Millisecond {
+		l.Set(1)
+		time.Sleep(time.Millisecond)
+	}
+	l.Set(2)
+	assert.Eventually(func() bool { return last() == 2 }, time.Second, time.Millisecond)
+	assert.GreaterOrEqual(l.Sent()-2, uint64(3))
+	assert.LessOrEqual(l.Sent()-2, uint64(7), "220ms at 20 Hz allows about five sends")
+
+	// Flush sends the held value at once.
+	time.Sleep(60 * time.Millisecond)
+	l.Set(3)
+	l.Set(4)
+	assert.NoError(l.Flush())
+	assert.Equal(4, last())
+	assert.NoError(l.Close())
+}
+
+func TestLimiterSlowSend(t *testing.T) {
+	assert := assert.New(t)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var sent []int
+	l := devices.NewLimiter(0, func(v int) error {
+		if v == 0 {
+			close(started)
+			<-release
+		}
+		mu.Lock()
+		sent = append(sent, v)
+		mu.Unlock()
+		if v == 2 {
+			return errors.New("endpoint failed")
+		}
+		return nil
+	})
+
+	done := make(chan error)
+	go func() { done <- l.Set(0) }()
+	<-started
+	assert.NoError(l.Set(1))
+	assert.NoError(l.Set(2), "values arriving during a send are held")
+	close(release)
+	assert.NoError(<-done)
+	assert.Eventually(func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(sent) == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal([]int{0, 2}, sent, "sends must not overlap and only the latest held value is sent")
+}
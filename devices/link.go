@@ -0,0 +1,176 @@
+package devices
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Endpoint is anything that can both report and accept values of type T, such as a motor fader or a DAW
+// parameter.
+type Endpoint[T any] interface {
+	Bind(callback func(T) error) func()
+	Set(value T) error
+}
+
+// Side identifies one end of a Link.
+type Side uint8
+
+const (
+	SideA Side = iota
+	SideB
+)
+
+// LinkConfig configures a Link between an endpoint of type A and one of type B.
+type LinkConfig[A, B any] struct {
+	// ToB and ToA convert values crossing the link. Both are required.
+	ToB func(A) B
+	ToA func(B) A
+
+	// EqualA and EqualB report whether two values are the same for the purpose of echo suppression. A value
+	// arriving from a side that equals the last value sent to it is treated as an echo and ignored. Leave them nil
+	// to disable value-based suppression.
+	EqualA func(A, A) bool
+	EqualB func(B, B) bool
+
+	// Hold ignores everything arriving from a side for this long after a value was sent to it, covering endpoints
+	// whose feedback lags or passes through intermediate values.
+	Hold time.Duration
+}
+
+// Link binds two endpoints to each other so that a change on either side is sent to the other, without the echo of
+// that change bouncing back.
+//
+// A side can be owned, e.g. while the user touches a motor fader. Values for an owned side are held back and the
+// latest is sent when it is released, so the control does not fight the user's hand.
+type Link[A, B any] struct {
+	cfg LinkConfig[A, B]
+
+	mu sync.Mutex
+	a  linkSide[A]
+	b  linkSide[B]
+
+	unbind []func()
+}
+
+// linkSide is the state the link keeps for one of its endpoints.
+type linkSide[T any] struct {
+	ep    Endpoint[T]
+	equal func(T, T) bool
+
+	sent    T
+	sentAt  time.Time
+	hasSent bool
+
+	owned   bool
+	held    T
+	hasHeld bool
+}
+
+// NewLink binds a and b to each other.
+func NewLink[A, B any](a Endpoint[A], b Endpoint[B], cfg LinkConfig[A, B]) *Link[A, B] {
+	l := &Link[A, B]{
+		cfg: cfg,
+		a:   linkSide[A]{ep: a, equal: cfg.EqualA},
+		b:   linkSide[B]{ep: b, equal: cfg.EqualB},
+	}
+	l.unbind = []func(){
+		a.Bind(func(v A) error {
+			return forward(&l.mu, &l.a, &l.b, l.cfg.Hold, l.cfg.ToB(v), v)
+		}),
+		b.Bind(func(v B) error {
+			return forward(&l.mu, &l.b, &l.a, l.cfg.Hold, l.cfg.ToA(v), v)
+		}),
+	}
+	return l
+}
+
+// NewSameLink links two endpoints of the same type without conversion.
+func NewSameLink[T any](a, b Endpoint[T], equal func(T, T) bool, hold time.Duration) *Link[T, T] {
+	same := func(v T) T { return v }
+	return NewLink(a, b, LinkConfig[T, T]{ToB: same, ToA: same, EqualA: equal, EqualB: equal, Hold: hold})
+}
+
+// WithinEpsilon returns an equality function that treats floats closer than epsilon as equal.
+func WithinEpsilon(epsilon float64) func(float64, float64) bool {
+	return func(x, y float64) bool {
+		return math.Abs(x-y) < epsilon
+	}
+}
+
+// forward sends converted, which came from the value v arriving on side from, to side to unless v is an echo or
+// side to is owned.
+func forward[F, T any](mu *sync.Mutex, from *linkSide[F], to *linkSide[T], hold time.Duration, converted T, v F) error {
+	mu.Lock()
+	if from.isEcho(v, hold) {
+		mu.Unlock()
+		return nil
+	}
+	// The side has moved on from what we sent it, so a later return to that value is genuine.
+	from.hasSent = false
+	if to.owned {
+		to.held, to.hasHeld = converted, true
+		mu.Unlock()
+		return nil
+	}
+	to.record(converted)
+	mu.Unlock()
+	return to.ep.Set(converted)
+}
+
+// isEcho reports whether v, arriving from this side, is feedback from a value recently sent to it.
+func (s *linkSide[T]) isEcho(v T, hold time.Duration) bool {
+	if !s.hasSent {
+		return false
+	}
+	if hold > 0 && time.Since(s.sentAt) < hold {
+		return true
+	}
+	return s.equal != nil && s.equal(s.sent, v)
+}
+
+// record notes that v is about to be sent to this side.
+func (s *linkSide[T]) record(v T) {
+	s.sent, s.sentAt, s.hasSent = v, time.Now(), true
+}
+
+// Touch takes ownership of side, holding back values sent to it until Release.
+func (l *Link[A, B]) Touch(side Side) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if side == SideA {
+		l.a.owned = true
+	} else {
+		l.b.owned = true
+	}
+}
+
+// Release gives up ownership of side and sends it the latest value held back while it was owned, if any.
+func (l *Link[A, B]) Release(side Side) error {
+	if side == SideA {
+		return release(&l.mu, &l.a)
+	}
+	return release(&l.mu, &l.b)
+}
+
+func release[T any](mu *sync.Mutex, s *linkSide[T]) error {
+	mu.Lock()
+	s.owned = false
+	if !s.hasHeld {
+		mu.Unlock()
+		return nil
+	}
+	v := s.held
+	var zero T
+	s.held, s.hasHeld = zero, false
+	s.record(v)
+	mu.Unlock()
+	return s.ep.Set(v)
+}
+
+// Unlink removes the bindings made by NewLink.
+func (l *Link[A, B]) Unlink() {
+	for _, unbind := range l.unbind {
+		unbind()
+	}
+}
@@ -0,0 +1,93 @@
+package devices_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jdginn/arpad/devices"
+)
+
+// echoEndpoint records values set on it and, like a motor fader or a DAW, reports them back as feedback.
+type echoEndpoint[T any] struct {
+	callback func(T) error
+	set      []T
+	echo     func(T) T
+}
+
+func (e *echoEndpoint[T]) Bind(callback func(T) error) func() {
+	e.callback = callback
+	return func() { e.callback = nil }
+}
+
+func (e *echoEndpoint[T]) Set(v T) error {
+	e.set = append(e.set, v)
+	if e.echo != nil {
+		return e.receive(e.echo(v))
+	}
+	return nil
+}
+
+// receive simulates a change reported by the endpoint itself.
+func (e *echoEndpoint[T]) receive(v T) error {
+	if e.callback == nil {
+		return nil
+	}
+	return e.callback(v)
+}
+
+func TestLink(t *testing.T) {
+	assert := assert.New(t)
+
+	fader := &echoEndpoint[uint16]{}
+	// The DAW rounds what it is sent before echoing it.
+	volume := &echoEndpoint[float64]{echo: func(v float64) float64 { return float64(int(v*1000)) / 1000 }}
+	l := devices.NewLink[uint16, float64](fader, volume, devices.LinkConfig[uint16, float64]{
+		ToB:    func(v uint16) float64 { return float64(v) / 16383 },
+		ToA:    func(v float64) uint16 { return uint16(v * 16383) },
+		EqualB: devices.WithinEpsilon(0.001),
+	})
+
+	assert.NoError(fader.receive(16383))
+	assert.NoError(fader.receive(8192))
+	assert.Equal([]float64{1, 8192.0 / 16383}, volume.set)
+	assert.Empty(fader.set, "the DAW's echo must not move the fader")
+
+	assert.NoError(volume.receive(0.25))
+	assert.Equal([]uint16{4095}, fader.set)
+	assert.NoError(volume.receive(0.5))
+	assert.NoError(volume.receive(0.25), "returning to a value sent earlier is a genuine change")
+	assert.Equal([]uint16{4095, 8191, 4095}, fader.set)
+
+	// While the fader is touched, DAW changes are held and the latest is sent on release.
+	l.Touch(devices.SideA)
+	assert.NoError(volume.receive(0.1))
+	assert.NoError(volume.receive(0.2))
+	assert.NoError(fader.receive(100))
+	assert.Len(fader.set, 3)
+	assert.NoError(l.Release(devices.SideA))
+	assert.Equal(uint16(3276), fader.set[3])
+	assert.NoError(l.Release(devices.SideA), "releasing with nothing held sends nothing")
+	assert.Len(fader.set, 4)
+
+	l.Unlink()
+	assert.NoError(fader.receive(1))
+	assert.Len(volume.set, 3)
+}
+
+func TestLinkHold(t *testing.T) {
+	assert := assert.New(t)
+
+	a := &echoEndpoint[float64]{}
+	b := &echoEndpoint[float64]{}
+	devices.NewSameLink[float64](a, b, nil, 50*time.Millisecond)
+
+	assert.NoError(a.receive(0.5))
+	assert.NoError(b.receive(0.4), "feedback passing through intermediate values is held off")
+	assert.Empty(a.set)
+
+	time.Sleep(60 * time.Millisecond)
+	assert.NoError(b.receive(0.3))
+	assert.Equal([]float64{0.3}, a.set)
+}
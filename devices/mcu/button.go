@@ -0,0 +1,325 @@
+package mcu
+
+// LEDState is the state of a button LED.
+type LEDState uint8
+
+const (
+	LEDOff      LEDState = 0x00
+	LEDFlashing LEDState = 0x01
+	LEDOn       LEDState = 0x7F
+)
+
+// Button is a button with an LED. All MCU buttons send Note On on channel 1 with velocity 127 when pressed and 0
+// when released, and the host lights the LED by sending the same note back.
+type Button struct {
+	s    *Surface
+	note uint8
+
+	On  *buttonOn
+	Off *buttonOff
+	LED *led
+}
+
+type buttonOn struct {
+	*Button
+}
+
+// Bind specifies the callback to run when this button is pressed. Only used in the Controller role.
+func (b *buttonOn) Bind(callback func() error) func() {
+	return b.s.d.Note(0, b.note).On.Bind(func(v uint8) error {
+		if b.s.role == Controller && v != 0 {
+			return callback()
+		}
+		return nil
+	})
+}
+
+// Set sends a press of this button to the host. Only used in the Emulator role.
+func (b *buttonOn) Set() error {
+	return b.s.d.Note(0, b.note).On.Set(0x7F)
+}
+
+type buttonOff struct {
+	*Button
+}
+
+// Bind specifies the callback to run when this button is released. Only used in the Controller role.
+func (b *buttonOff) Bind(callback func() error) func() {
+	return b.s.d.Note(0, b.note).On.Bind(func(v uint8) error {
+		if b.s.role == Controller && v == 0 {
+			return callback()
+		}
+		return nil
+	})
+}
+
+// Set sends a release of this button to the host. Only used in the Emulator role.
+func (b *buttonOff) Set() error {
+	return b.s.d.Note(0, b.note).On.Set(0x00)
+}
+
+type led struct {
+	*Button
+}
+
+// Set sets the state of this LED on the surface. Only used in the Controller role.
+func (l *led) Set(state LEDState) error {
+	return l.s.d.Note(0, l.note).On.Set(uint8(state))
+}
+
+// Bind specifies the callback to run when the host changes this LED. Only used in the Emulator role.
+func (l *led) Bind(callback func(LEDState) error) func() {
+	return l.s.d.Note(0, l.note).On.Bind(func(v uint8) error {
+		if l.s.role != Emulator {
+			return nil
+		}
+		switch v {
+		case 0x00:
+			return callback(LEDOff)
+		case 0x01:
+			return callback(LEDFlashing)
+		default:
+			return callback(LEDOn)
+		}
+	})
+}
+
+func (s *Surface) newButton(note uint8) *Button {
+	b := &Button{
+		s:    s,
+		note: note,
+	}
+	b.On = &buttonOn{b}
+	b.Off = &buttonOff{b}
+	b.LED = &led{b}
+	return b
+}
+
+// Note numbers for the standard MCU button map.
+const (
+	noteRec         uint8 = 0x00
+	noteSolo        uint8 = 0x08
+	noteMute        uint8 = 0x10
+	noteSelect      uint8 = 0x18
+	noteVPotPush    uint8 = 0x20
+	noteFaderTouch  uint8 = 0x68
+	noteSMPTELED    uint8 = 0x71
+	noteBeatsLED    uint8 = 0x72
+	noteRudeSoloLED uint8 = 0x73
+)
+
+type EncoderAssign struct {
+	TRACK        *Button
+	SEND         *Button
+	PAN_SURROUND *Button
+	PLUGIN       *Button
+	EQ           *Button
+	INSTRUMENT   *Button
+}
+
+func (s *Surface) newEncoderAssign() *EncoderAssign {
+	return &EncoderAssign{
+		TRACK:        s.newButton(0x28),
+		SEND:         s.newButton(0x29),
+		PAN_SURROUND: s.newButton(0x2A),
+		PLUGIN:       s.newButton(0x2B),
+		EQ:           s.newButton(0x2C),
+		INSTRUMENT:   s.newButton(0x2D),
+	}
+}
+
+type Page struct {
+	BANK_L    *Button
+	BANK_R    *Button
+	CHANNEL_L *Button
+	CHANNEL_R *Button
+}
+
+func (s *Surface) newPage() *Page {
+	return &Page{
+		BANK_L:    s.newButton(0x2E),
+		BANK_R:    s.newButton(0x2F),
+		CHANNEL_L: s.newButton(0x30),
+		CHANNEL_R: s.newButton(0x31),
+	}
+}
+
+type Display struct {
+	FLIP        *Button
+	GLOBAL_VIEW *Button
+	NAME_VALUE  *Button
+	SMPTE_BEATS *Button
+
+	// Indicator-only LEDs next to the timecode display
+	SMPTE     *Button
+	BEATS     *Button
+	RUDE_SOLO *Button
+}
+
+func (s *Surface) newDisplay() *Display {
+	return &Display{
+		FLIP:        s.newButton(0x32),
+		GLOBAL_VIEW: s.newButton(0x33),
+		NAME_VALUE:  s.newButton(0x34),
+		SMPTE_BEATS: s.newButton(0x35),
+		SMPTE:       s.newButton(noteSMPTELED),
+		BEATS:       s.newButton(noteBeatsLED),
+		RUDE_SOLO:   s.newButton(noteRudeSoloLED),
+	}
+}
+
+type Function struct {
+	F1 *Button
+	F2 *Button
+	F3 *Button
+	F4 *Button
+	F5 *Button
+	F6 *Button
+	F7 *Button
+	F8 *Button
+}
+
+func (s *Surface) newFunction() *Function {
+	return &Function{
+		F1: s.newButton(0x36),
+		F2: s.newButton(0x37),
+		F3: s.newButton(0x38),
+		F4: s.newButton(0x39),
+		F5: s.newButton(0x3A),
+		F6: s.newButton(0x3B),
+		F7: s.newButton(0x3C),
+		F8: s.newButton(0x3D),
+	}
+}
+
+type View struct {
+	MIDI_TRACKS  *Button
+	INPUTS       *Button
+	AUDIO_TRACKS *Button
+	AUDIO_INST   *Button
+	AUX          *Button
+	BUSSES       *Button
+	OUTPUTS      *Button
+	USER         *Button
+}
+
+func (s *Surface) newView() *View {
+	return &View{
+		MIDI_TRACKS:  s.newButton(0x3E),
+		INPUTS:       s.newButton(0x3F),
+		AUDIO_TRACKS: s.newButton(0x40),
+		AUDIO_INST:   s.newButton(0x41),
+		AUX:          s.newButton(0x42),
+		BUSSES:       s.newButton(0x43),
+		OUTPUTS:      s.newButton(0x44),
+		USER:         s.newButton(0x45),
+	}
+}
+
+type Modify struct {
+	SHIFT   *Button
+	OPTION  *Button
+	CONTROL *Button
+	ALT     *Button
+}
+
+func (s *Surface) newModify() *Modify {
+	return &Modify{
+		SHIFT:   s.newButton(0x46),
+		OPTION:  s.newButton(0x47),
+		CONTROL: s.newButton(0x48),
+		ALT:     s.newButton(0x49),
+	}
+}
+
+type Automation struct {
+	READ_OFF *Button
+	WRITE    *Button
+	TRIM     *Button
+	TOUCH    *Button
+	LATCH    *Button
+	GROUP    *Button
+}
+
+func (s *Surface) newAutomation() *Automation {
+	return &Automation{
+		READ_OFF: s.newButton(0x4A),
+		WRITE:    s.newButton(0x4B),
+		TRIM:     s.newButton(0x4C),
+		TOUCH:    s.newButton(0x4D),
+		LATCH:    s.newButton(0x4E),
+		GROUP:    s.newButton(0x4F),
+	}
+}
+
+type Utility struct {
+	SAVE   *Button
+	UNDO   *Button
+	CANCEL *Button
+	ENTER  *Button
+}
+
+func (s *Surface) newUtility() *Utility {
+	return &Utility{
+		SAVE:   s.newButton(0x50),
+		UNDO:   s.newButton(0x51),
+		CANCEL: s.newButton(0x52),
+		ENTER:  s.newButton(0x53),
+	}
+}
+
+type Transport struct {
+	Marker  *Button
+	Nudge   *Button
+	Cycle   *Button
+	Drop    *Button
+	Replace *Button
+	Click   *Button
+	Solo    *Button
+	REW     *Button
+	FF      *Button
+	STOP    *Button
+	PLAY    *Button
+	RECORD  *Button
+}
+
+func (s *Surface) newTransport() *Transport {
+	return &Transport{
+		Marker:  s.newButton(0x54),
+		Nudge:   s.newButton(0x55),
+		Cycle:   s.newButton(0x56),
+		Drop:    s.newButton(0x57),
+		Replace: s.newButton(0x58),
+		Click:   s.newButton(0x59),
+		Solo:    s.newButton(0x5A),
+		REW:     s.newButton(0x5B),
+		FF:      s.newButton(0x5C),
+		STOP:    s.newButton(0x5D),
+		PLAY:    s.newButton(0x5E),
+		RECORD:  s.newButton(0x5F),
+	}
+}
+
+type Navigation struct {
+	UP     *Button
+	DOWN   *Button
+	LEFT   *Button
+	RIGHT  *Button
+	ZOOM   *Button
+	SCRUB  *Button
+	USER_A *Button
+	USER_B *Button
+}
+
+func (s *Surface) newNavigation() *Navigation {
+	return &Navigation{
+		UP:     s.newButton(0x60),
+		DOWN:   s.newButton(0x61),
+		LEFT:   s.newButton(0x62),
+		RIGHT:  s.newButton(0x63),
+		ZOOM:   s.newButton(0x64),
+		SCRUB:  s.newButton(0x65),
+		USER_A: s.newButton(0x66),
+		USER_B: s.newButton(0x67),
+	}
+}
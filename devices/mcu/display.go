@@ -0,0 +1,271 @@
+package mcu
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	// LCDWidth is the number of characters on each line of the LCD.
+	LCDWidth = 56
+	// LCDLines is the number of lines on the LCD.
+	LCDLines = 2
+	// LCDStripWidth is the number of characters above each channel strip.
+	LCDStripWidth = LCDWidth / NumStrips
+
+	// TimecodeDigits is the number of 7-segment digits in the timecode display.
+	TimecodeDigits = 10
+	// AssignmentDigits is the number of 7-segment digits in the assignment display.
+	AssignmentDigits = 2
+
+	ccTimecode   uint8 = 0x40 // rightmost timecode digit; digits run leftwards up to 0x49
+	ccAssignment uint8 = 0x4A // rightmost assignment digit; 0x4B is the left digit
+)
+
+// LCD is the 2x56 character backlit display above the channel strips.
+type LCD struct {
+	s *Surface
+
+	mu  sync.Mutex
+	buf [LCDWidth * LCDLines]byte
+}
+
+func (s *Surface) newLCD() *LCD {
+	l := &LCD{s: s}
+	for i := range l.buf {
+		l.buf[i] = ' '
+	}
+	return l
+}
+
+// Set writes text starting at the given character offset, where 0 is the start of the top line and LCDWidth is the
+// start of the bottom line. Text running past the end of the display is truncated.
+func (l *LCD) Set(offset int, text string) error {
+	if offset < 0 || offset >= len(l.buf) {
+		return fmt.Errorf("invalid LCD offset %d: must be between 0 and %d", offset, len(l.buf)-1)
+	}
+	b := lcdBytes(text)
+	if len(b) > len(l.buf)-offset {
+		b = b[:len(l.buf)-offset]
+	}
+	l.mu.Lock()
+	copy(l.buf[offset:], b)
+	l.mu.Unlock()
+	return l.s.sendSysEx(sysExLCD, append([]byte{uint8(offset)}, b...)...)
+}
+
+// SetStrip writes text in the cell above the given strip on the given line (0 for top, 1 for bottom). Text is
+// truncated to LCDStripWidth-1 characters and padded with spaces so that adjacent cells stay visually separate.
+func (l *LCD) SetStrip(strip, line int, text string) error {
+	if strip < 0 || strip >= NumStrips {
+		return fmt.Errorf("invalid strip %d: must be between 0 and %d", strip, NumStrips-1)
+	}
+	if line < 0 || line >= LCDLines {
+		return fmt.Errorf("invalid LCD line %d: must be 0 or 1", line)
+	}
+	if len(text) > LCDStripWidth-1 {
+		text = text[:LCDStripWidth-1]
+	}
+	text += strings.Repeat(" ", LCDStripWidth-len(text))
+	return l.Set(line*LCDWidth+strip*LCDStripWidth, text)
+}
+
+// Lines returns the current contents of the display as written by Set or received from the host.
+func (l *LCD) Lines() (top, bottom string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return string(l.buf[:LCDWidth]), string(l.buf[LCDWidth:])
+}
+
+// Bind specifies the callback to run with the full display contents each time the host writes to the LCD. Only
+// used in the Emulator role.
+func (l *LCD) Bind(callback func(top, bottom string) error) func() {
+	return l.s.d.SysEx.Match(append(l.s.sysExHeader(), sysExLCD)).Bind(func(data []byte) error {
+		// data: header (4 bytes), command, offset, characters
+		if len(data) < 6 {
+			return nil
+		}
+		offset := int(data[5])
+		if offset >= len(l.buf) {
+			return fmt.Errorf("invalid LCD offset %d from host", offset)
+		}
+		l.mu.Lock()
+		copy(l.buf[offset:], data[6:])
+		l.mu.Unlock()
+		return callback(l.Lines())
+	})
+}
+
+// lcdBytes converts text to the LCD's 7-bit character set, replacing anything unprintable with a space.
+func lcdBytes(text string) []byte {
+	b := make([]byte, 0, len(text))
+	for _, r := range text {
+		if r < 0x20 || r > 0x7E {
+			r = ' '
+		}
+		b = append(b, byte(r))
+	}
+	return b
+}
+
+// encodeSegment converts a character to the MCU 7-segment character set. Letters map to 0x01-0x1A, digits and
+// punctuation keep their ASCII codes, and bit 6 lights the decimal point.
+func encodeSegment(c byte, dot bool) uint8 {
+	if c >= 'a' && c <= 'z' {
+		c -= 'a' - 'A'
+	}
+	var v uint8
+	switch {
+	case c >= 0x40 && c <= 0x5F:
+		v = c - 0x40
+	case c >= 0x20 && c <= 0x3F:
+		v = c
+	default:
+		v = ' '
+	}
+	if dot {
+		v |= 0x40
+	}
+	return v
+}
+
+// decodeSegment is the inverse of encodeSegment.
+func decodeSegment(v uint8) (c byte, dot bool) {
+	c = v & 0x3F
+	if c < 0x20 {
+		c += 0x40
+	}
+	return c, v&0x40 != 0
+}
+
+// segmentCells splits text into one cell per digit. A '.' lights the decimal point of the preceding character
+// rather than taking a digit of its own.
+func segmentCells(text string) []uint8 {
+	var cells []uint8
+	for i := 0; i < len(text); i++ {
+		if text[i] == '.' && len(cells) > 0 && cells[len(cells)-1]&0x40 == 0 {
+			cells[len(cells)-1] |= 0x40
+			continue
+		}
+		cells = append(cells, encodeSegment(text[i], false))
+	}
+	return cells
+}
+
+// segmentDisplay is a row of 7-segment digits addressed by consecutive controllers, rightmost digit first.
+type segmentDisplay struct {
+	s      *Surface
+	baseCC uint8
+
+	mu    sync.Mutex
+	cells []uint8
+}
+
+func (d *segmentDisplay) set(text string) error {
+	cells := segmentCells(text)
+	if len(cells) > len(d.cells) {
+		return fmt.Errorf("text %q needs %d digits; display has %d", text, len(cells), len(d.cells))
+	}
+	// Right-align
+	padded := make([]uint8, len(d.cells))
+	for i := range padded {
+		padded[i] = ' '
+	}
+	copy(padded[len(padded)-len(cells):], cells)
+
+	d.mu.Lock()
+	copy(d.cells, padded)
+	d.mu.Unlock()
+	for i, v := range padded {
+		cc := d.baseCC + uint8(len(padded)-1-i)
+		if err := d.s.d.CC(0, cc).Set(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *segmentDisplay) text() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var sb strings.Builder
+	for _, v := range d.cells {
+		c, dot := decodeSegment(v)
+		sb.WriteByte(c)
+		if dot {
+			sb.WriteByte('.')
+		}
+	}
+	return sb.String()
+}
+
+func (d *segmentDisplay) bind(callback func(string) error) func() {
+	var unbinds []func()
+	for i := range d.cells {
+		cc := d.baseCC + uint8(i)
+		idx := len(d.cells) - 1 - i
+		unbinds = append(unbinds, d.s.d.CC(0, cc).Bind(func(v uint8) error {
+			d.mu.Lock()
+			d.cells[idx] = v
+			d.mu.Unlock()
+			return callback(d.text())
+		}))
+	}
+	return func() {
+		for _, unbind := range unbinds {
+			unbind()
+		}
+	}
+}
+
+// Timecode is the 10-digit 7-segment display showing SMPTE time or bars/beats.
+type Timecode struct {
+	segmentDisplay
+}
+
+func (s *Surface) newTimecode() *Timecode {
+	return &Timecode{segmentDisplay{s: s, baseCC: ccTimecode, cells: make([]uint8, TimecodeDigits)}}
+}
+
+// Set shows text right-aligned on the display. A '.' lights the decimal point of the preceding digit, so
+// "01.02.03.04" fits in 8 digits.
+func (t *Timecode) Set(text string) error {
+	return t.set(text)
+}
+
+// Text returns the current contents of the display.
+func (t *Timecode) Text() string {
+	return t.text()
+}
+
+// Bind specifies the callback to run with the full display contents each time the host changes a digit. Only
+// used in the Emulator role.
+func (t *Timecode) Bind(callback func(string) error) func() {
+	return t.bind(callback)
+}
+
+// Assignment is the 2-digit 7-segment display to the left of the timecode display.
+type Assignment struct {
+	segmentDisplay
+}
+
+func (s *Surface) newAssignment() *Assignment {
+	return &Assignment{segmentDisplay{s: s, baseCC: ccAssignment, cells: make([]uint8, AssignmentDigits)}}
+}
+
+// Set shows text right-aligned on the display.
+func (a *Assignment) Set(text string) error {
+	return a.set(text)
+}
+
+// Text returns the current contents of the display.
+func (a *Assignment) Text() string {
+	return a.text()
+}
+
+// Bind specifies the callback to run with the full display contents each time the host changes a digit. Only
+// used in the Emulator role.
+func (a *Assignment) Bind(callback func(string) error) func() {
+	return a.bind(callback)
+}
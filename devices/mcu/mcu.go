@@ -0,0 +1,184 @@
+// Package mcu implements the Mackie Control Universal (MCU) protocol.
+//
+// A Surface can face either direction:
+//
+//   - New wraps a control surface that speaks MCU (e.g. an Icon, a Faderport 8 in MCU mode or an SSL UF8). Button
+//     presses, fader moves and V-Pot turns are bindable inputs; LEDs, motor faders, rings, displays and meters are
+//     outputs.
+//   - NewEmulator presents arpad itself as an MCU surface to a DAW, typically over a virtual MIDI port. The roles
+//     reverse: LEDs, faders, rings, displays and meters are bindable inputs from the DAW, and button presses,
+//     fader moves and V-Pot turns are sent to it.
+//
+// Most messages are identical in both directions; the role decides how ambiguous messages (button notes, which
+// carry presses toward the host and LED states toward the surface) are routed.
+package mcu
+
+import (
+	"context"
+	"log/slog"
+
+	dev "github.com/jdginn/arpad/devices"
+	"github.com/jdginn/arpad/logging"
+
+	midi "gitlab.com/gomidi/midi/v2"
+)
+
+var log *slog.Logger
+
+func init() {
+	log = logging.Get(logging.MIDI_IN)
+}
+
+// Role selects which side of the MCU protocol a Surface plays.
+type Role int
+
+const (
+	// Controller drives a physical MCU surface.
+	Controller Role = iota
+	// Emulator presents arpad as an MCU surface to a host DAW.
+	Emulator
+)
+
+func (r Role) String() string {
+	switch r {
+	case Controller:
+		return "controller"
+	case Emulator:
+		return "emulator"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	// DeviceMCU is the SysEx device ID of a main MCU unit.
+	DeviceMCU uint8 = 0x14
+	// DeviceExtender is the SysEx device ID of an MCU extender.
+	DeviceExtender uint8 = 0x15
+
+	// NumStrips is the number of channel strips on an MCU unit.
+	NumStrips = 8
+)
+
+// SysEx commands understood by MCU surfaces and hosts.
+const (
+	sysExDeviceQuery     uint8 = 0x00
+	sysExHostQuery       uint8 = 0x01
+	sysExHostReply       uint8 = 0x02
+	sysExConfirm         uint8 = 0x03
+	sysExLCD             uint8 = 0x12
+	sysExVersionReq      uint8 = 0x13
+	sysExVersionReply    uint8 = 0x14
+	emulatorSerial             = "ARPAD01"
+	emulatorVersion            = "V1.02"
+	emulatorChallengeLen       = 4
+)
+
+// Surface represents an MCU unit: eight channel strips, a master fader, the LCD, the timecode and assignment
+// displays and the standard button map.
+type Surface struct {
+	d        *dev.MidiDevice
+	role     Role
+	deviceID uint8
+
+	Strips     []*Strip
+	Master     *Fader
+	LCD        *LCD
+	Timecode   *Timecode
+	Assignment *Assignment
+	Jog        *VPot
+
+	EncoderAssign *EncoderAssign
+	Page          *Page
+	Display       *Display
+	View          *View
+	Function      *Function
+	Modify        *Modify
+	Automation    *Automation
+	Utility       *Utility
+	Transport     *Transport
+	Navigation    *Navigation
+}
+
+// New returns a Surface that drives a physical MCU surface on d.
+func New(d *dev.MidiDevice) *Surface {
+	return newSurface(d, Controller, DeviceMCU)
+}
+
+// NewExtender returns a Surface that drives a physical MCU extender on d. Extenders have channel strips and an
+// LCD but no master section; only Strips and LCD should be used.
+func NewExtender(d *dev.MidiDevice) *Surface {
+	return newSurface(d, Controller, DeviceExtender)
+}
+
+// NewEmulator returns a Surface that presents itself to a host DAW as an MCU on d.
+func NewEmulator(d *dev.MidiDevice) *Surface {
+	s := newSurface(d, Emulator, DeviceMCU)
+	s.answerHandshake()
+	return s
+}
+
+func newSurface(d *dev.MidiDevice, role Role, deviceID uint8) *Surface {
+	s := &Surface{
+		d:        d,
+		role:     role,
+		deviceID: deviceID,
+	}
+	for i := uint8(0); i < NumStrips; i++ {
+		s.Strips = append(s.Strips, s.newStrip(i))
+	}
+	s.Master = s.newFader(8)
+	s.LCD = s.newLCD()
+	s.Timecode = s.newTimecode()
+	s.Assignment = s.newAssignment()
+	s.Jog = s.newVPot(ccJog)
+	s.EncoderAssign = s.newEncoderAssign()
+	s.Page = s.newPage()
+	s.Display = s.newDisplay()
+	s.View = s.newView()
+	s.Function = s.newFunction()
+	s.Modify = s.newModify()
+	s.Automation = s.newAutomation()
+	s.Utility = s.newUtility()
+	s.Transport = s.newTransport()
+	s.Navigation = s.newNavigation()
+	return s
+}
+
+// Role returns which side of the protocol this surface plays.
+func (s *Surface) Role() Role {
+	return s.role
+}
+
+// Run runs the underlying MIDI device until ctx is cancelled.
+func (s *Surface) Run(ctx context.Context) error {
+	return s.d.Run(ctx)
+}
+
+func (s *Surface) sysExHeader() []byte {
+	return []byte{0x00, 0x00, 0x66, s.deviceID}
+}
+
+func (s *Surface) sendSysEx(command uint8, data ...byte) error {
+	b := append(s.sysExHeader(), command)
+	b = append(b, data...)
+	return s.d.SysEx.Set(midi.SysEx(b))
+}
+
+// answerHandshake responds to a host's device and version queries the way an MCU does, so that hosts which
+// insist on the handshake will bring the emulated surface online.
+func (s *Surface) answerHandshake() {
+	header := s.sysExHeader()
+	s.d.SysEx.Match(append(header, sysExDeviceQuery)).Bind(func([]byte) error {
+		log.Info("MCU host sent device query; replying", slog.String("role", s.role.String()))
+		reply := append([]byte(emulatorSerial), make([]byte, emulatorChallengeLen)...)
+		return s.sendSysEx(sysExHostQuery, reply...)
+	})
+	s.d.SysEx.Match(append(header, sysExHostReply)).Bind(func([]byte) error {
+		log.Info("MCU host confirmed connection", slog.String("role", s.role.String()))
+		return s.sendSysEx(sysExConfirm, []byte(emulatorSerial)...)
+	})
+	s.d.SysEx.Match(append(header, sysExVersionReq)).Bind(func([]byte) error {
+		return s.sendSysEx(sysExVersionReply, []byte(emulatorVersion)...)
+	})
+}
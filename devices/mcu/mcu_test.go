@@ -0,0 +1,120 @@
+package mcu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	midi "gitlab.com/gomidi/midi/v2"
+
+	dev "github.com/jdginn/arpad/devices"
+	devtest "github.com/jdginn/arpad/devices/devicestesting"
+)
+
+func TestLCD(t *testing.T) {
+	assert := assert.New(t)
+
+	out := devtest.NewMockMIDIPort()
+	s := New(dev.NewMidiDevice(devtest.NewMockMIDIPort(), out))
+
+	assert.NoError(s.LCD.SetStrip(1, 1, "Vocals Lead"))
+	assert.Equal([]byte{0xF0, 0x00, 0x00, 0x66, 0x14, 0x12, 63, 'V', 'o', 'c', 'a', 'l', 's', ' ', 0xF7},
+		out.GetSentMessages()[0].Bytes())
+	_, bottom := s.LCD.Lines()
+	assert.Equal("       Vocals ", bottom[:14])
+
+	assert.Error(s.LCD.SetStrip(8, 0, "x"))
+	assert.Error(s.LCD.Set(112, "x"))
+}
+
+func TestTimecode(t *testing.T) {
+	assert := assert.New(t)
+
+	out := devtest.NewMockMIDIPort()
+	s := New(dev.NewMidiDevice(devtest.NewMockMIDIPort(), out))
+
+	assert.NoError(s.Assignment.Set("Mx"))
+	assert.Equal([]midi.Message{
+		midi.ControlChange(0, 0x4B, 0x0D), // 'M'
+		midi.ControlChange(0, 0x4A, 0x18), // 'X'
+	}, out.GetSentMessages())
+
+	assert.NoError(s.Timecode.Set("1.02"))
+	sent := out.GetSentMessages()[2:]
+	assert.Len(sent, TimecodeDigits)
+	assert.Equal(midi.ControlChange(0, 0x42, 0x31|0x40), sent[7], "dot should merge into the preceding digit")
+	assert.Equal(midi.ControlChange(0, 0x40, 0x32), sent[9], "last character should land on the rightmost digit")
+	assert.Equal("       1.02", s.Timecode.Text())
+
+	assert.Error(s.Assignment.Set("ABC"))
+}
+
+func TestRelative(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(3, DecodeRelative(0x03))
+	assert.Equal(-5, DecodeRelative(0x45))
+	assert.Equal(uint8(0x45), EncodeRelative(-5))
+	assert.Equal(uint8(0x3F), EncodeRelative(100))
+}
+
+func TestControllerRouting(t *testing.T) {
+	assert := assert.New(t)
+
+	in := devtest.NewMockMIDIPort()
+	s := New(dev.NewMidiDevice(in, devtest.NewMockMIDIPort()))
+	defer devtest.RunDevice(t, s.Run, s.d.IsConnected)()
+
+	var presses, releases, leds, ticks int
+	s.Transport.PLAY.On.Bind(func() error { presses++; return nil })
+	s.Transport.PLAY.Off.Bind(func() error { releases++; return nil })
+	s.Transport.PLAY.LED.Bind(func(LEDState) error { leds++; return nil })
+	s.Strips[2].VPot.Bind(func(v int) error { ticks += v; return nil })
+
+	in.SimulateReceive(midi.NoteOn(0, 0x5E, 0x7F))
+	in.SimulateReceive(midi.NoteOn(0, 0x5E, 0x00))
+	in.SimulateReceive(midi.ControlChange(0, 0x12, 0x02))
+	in.SimulateReceive(midi.ControlChange(0, 0x12, 0x41))
+
+	assert.Equal(1, presses)
+	assert.Equal(1, releases)
+	assert.Equal(0, leds, "LED bindings are only used when emulating a surface")
+	assert.Equal(1, ticks)
+}
+
+func TestEmulator(t *testing.T) {
+	assert := assert.New(t)
+
+	in := devtest.NewMockMIDIPort()
+	out := devtest.NewMockMIDIPort()
+	s := NewEmulator(dev.NewMidiDevice(in, out))
+	defer devtest.RunDevice(t, s.Run, s.d.IsConnected)()
+
+	var presses int
+	var led LEDState
+	var level float64
+	var top string
+	s.Transport.PLAY.On.Bind(func() error { presses++; return nil })
+	s.Transport.PLAY.LED.Bind(func(v LEDState) error { led = v; return nil })
+	s.Strips[3].Meter.Bind(func(v float64) error { level = v; return nil })
+	s.LCD.Bind(func(t, _ string) error { top = t; return nil })
+
+	in.SimulateReceive(midi.NoteOn(0, 0x5E, 0x01))
+	in.SimulateReceive(midi.AfterTouch(0, 0x3C))
+	in.SimulateReceive(midi.AfterTouch(0, 0x2C))
+	in.SimulateReceive(midi.SysEx([]byte{0x00, 0x00, 0x66, 0x14, 0x12, 0x07, 'D', 'r', 'u', 'm', 's'}))
+
+	assert.Equal(0, presses, "host LED commands must not look like button presses")
+	assert.Equal(LEDFlashing, led)
+	assert.Equal(1.0, level)
+	assert.Equal("       Drums ", top[:13])
+
+	in.SimulateReceive(midi.SysEx([]byte{0x00, 0x00, 0x66, 0x14, 0x00}))
+	sent := out.GetSentMessages()
+	assert.NotEmpty(sent)
+	assert.Equal([]byte{0xF0, 0x00, 0x00, 0x66, 0x14, 0x01, 'A', 'R', 'P', 'A', 'D', '0', '1', 0, 0, 0, 0, 0xF7},
+		sent[len(sent)-1].Bytes(), "device query should be answered")
+
+	assert.NoError(s.Strips[0].VPot.Set(-2))
+	assert.NoError(s.Transport.PLAY.On.Set())
+	sent = out.GetSentMessages()
+	assert.Equal([]midi.Message{midi.ControlChange(0, 0x10, 0x42), midi.NoteOn(0, 0x5E, 0x7F)}, sent[len(sent)-2:])
+}
@@ -0,0 +1,210 @@
+package mcu
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	ccVPot     uint8 = 0x10 // V-Pot rotation, CC 16-23
+	ccVPotRing uint8 = 0x30 // V-Pot LED ring, CC 48-55
+	ccJog      uint8 = 0x3C // Jog wheel rotation
+
+	// Meter levels are sent as channel pressure with the strip in the high nibble and the level in the low nibble.
+	meterMaxLevel      uint8 = 0x0C
+	meterSetOverload   uint8 = 0x0E
+	meterClearOverload uint8 = 0x0F
+)
+
+// Strip is a single MCU channel strip.
+type Strip struct {
+	Fader    *Fader
+	VPot     *VPot
+	Ring     *Ring
+	Meter    *Meter
+	VPotPush *Button
+	Rec      *Button
+	Solo     *Button
+	Mute     *Button
+	Select   *Button
+}
+
+func (s *Surface) newStrip(id uint8) *Strip {
+	return &Strip{
+		Fader:    s.newFader(id),
+		VPot:     s.newVPot(ccVPot + id),
+		Ring:     &Ring{s: s, cc: ccVPotRing + id},
+		Meter:    &Meter{s: s, strip: id},
+		VPotPush: s.newButton(noteVPotPush + id),
+		Rec:      s.newButton(noteRec + id),
+		Solo:     s.newButton(noteSolo + id),
+		Mute:     s.newButton(noteMute + id),
+		Select:   s.newButton(noteSelect + id),
+	}
+}
+
+// Fader is a touch-sensitive motorized fader. Position is sent as 14-bit pitch bend on the fader's channel in
+// both directions.
+type Fader struct {
+	s       *Surface
+	channel uint8
+
+	// Touch is pressed when a finger lands on the fader and released when it lifts.
+	Touch *Button
+}
+
+func (s *Surface) newFader(channel uint8) *Fader {
+	return &Fader{
+		s:       s,
+		channel: channel,
+		Touch:   s.newButton(noteFaderTouch + channel),
+	}
+}
+
+// Bind specifies the callback to run when the fader moves (Controller) or the host moves it (Emulator).
+func (f *Fader) Bind(callback func(uint16) error) func() {
+	return f.s.d.PitchBend(f.channel).Bind(callback)
+}
+
+// Set moves the motorized fader (Controller) or reports a fader move to the host (Emulator).
+func (f *Fader) Set(val uint16) error {
+	return f.s.d.PitchBend(f.channel).Set(val)
+}
+
+// VPot is a relative rotary encoder. Each message carries a direction in bit 6 (set for counter-clockwise) and
+// a tick count in bits 0-5.
+type VPot struct {
+	s  *Surface
+	cc uint8
+}
+
+func (s *Surface) newVPot(cc uint8) *VPot {
+	return &VPot{s: s, cc: cc}
+}
+
+// DecodeRelative converts an MCU relative encoder value into a signed number of ticks.
+func DecodeRelative(v uint8) int {
+	ticks := int(v & 0x3F)
+	if v&0x40 != 0 {
+		return -ticks
+	}
+	return ticks
+}
+
+// EncodeRelative converts a signed number of ticks into an MCU relative encoder value. Magnitudes beyond 63
+// are clamped.
+func EncodeRelative(ticks int) uint8 {
+	var v uint8
+	if ticks < 0 {
+		v = 0x40
+		ticks = -ticks
+	}
+	return v | uint8(min(ticks, 0x3F))
+}
+
+// Bind specifies the callback to run with the signed number of ticks each time the V-Pot is turned.
+func (e *VPot) Bind(callback func(int) error) func() {
+	return e.s.d.CC(0, e.cc).Bind(func(v uint8) error {
+		return callback(DecodeRelative(v))
+	})
+}
+
+// Set reports a V-Pot turn of the given signed number of ticks to the host. Only used in the Emulator role.
+func (e *VPot) Set(ticks int) error {
+	if ticks == 0 {
+		return nil
+	}
+	return e.s.d.CC(0, e.cc).Set(EncodeRelative(ticks))
+}
+
+// RingMode selects how the V-Pot LED ring renders its position.
+type RingMode uint8
+
+const (
+	RingDot      RingMode = 0 // a single LED at the position
+	RingBoostCut RingMode = 1 // LEDs from the center to the position
+	RingWrap     RingMode = 2 // LEDs from the left to the position
+	RingSpread   RingMode = 3 // LEDs spreading symmetrically from the center
+)
+
+// Ring is the 11-segment LED ring around a V-Pot, plus the center LED below it.
+type Ring struct {
+	s  *Surface
+	cc uint8
+}
+
+// Set lights the ring. pos ranges from 0 (all off) to 11; center lights the LED below the ring.
+func (r *Ring) Set(mode RingMode, pos uint8, center bool) error {
+	if pos > 11 {
+		return fmt.Errorf("invalid ring position %d: must be between 0 and 11", pos)
+	}
+	v := uint8(mode&0x03)<<4 | pos
+	if center {
+		v |= 0x40
+	}
+	return r.s.d.CC(0, r.cc).Set(v)
+}
+
+// SetNorm lights the ring to show v in [0.0, 1.0] using the given mode.
+func (r *Ring) SetNorm(mode RingMode, v float64) error {
+	v = math.Max(0, math.Min(1, v))
+	return r.Set(mode, 1+uint8(math.Round(v*10)), false)
+}
+
+// Bind specifies the callback to run when the host changes the ring. Only used in the Emulator role.
+func (r *Ring) Bind(callback func(mode RingMode, pos uint8, center bool) error) func() {
+	return r.s.d.CC(0, r.cc).Bind(func(v uint8) error {
+		return callback(RingMode(v>>4&0x03), v&0x0F, v&0x40 != 0)
+	})
+}
+
+// Meter is a strip's signal level meter. MCU surfaces decay meters on their own, so hosts only send new peaks.
+type Meter struct {
+	s     *Surface
+	strip uint8
+}
+
+// Set shows level in [0.0, 1.0] on the meter.
+func (m *Meter) Set(level float64) error {
+	level = math.Max(0, math.Min(1, level))
+	return m.send(uint8(math.Round(level * float64(meterMaxLevel))))
+}
+
+// SetOverload lights or clears the meter's overload indicator.
+func (m *Meter) SetOverload(on bool) error {
+	if on {
+		return m.send(meterSetOverload)
+	}
+	return m.send(meterClearOverload)
+}
+
+func (m *Meter) send(v uint8) error {
+	return m.s.d.Aftertouch(0).Set(m.strip<<4 | v)
+}
+
+// Bind specifies the callback to run when the host sends a meter level for this strip. Only used in the
+// Emulator role. level is in [0.0, 1.0]; overload messages are delivered to BindOverload instead.
+func (m *Meter) Bind(callback func(level float64) error) func() {
+	return m.s.d.Aftertouch(0).Bind(func(v uint8) error {
+		if v>>4 != m.strip || v&0x0F > meterMaxLevel {
+			return nil
+		}
+		return callback(float64(v&0x0F) / float64(meterMaxLevel))
+	})
+}
+
+// BindOverload specifies the callback to run when the host sets or clears this strip's overload indicator.
+func (m *Meter) BindOverload(callback func(on bool) error) func() {
+	return m.s.d.Aftertouch(0).Bind(func(v uint8) error {
+		if v>>4 != m.strip {
+			return nil
+		}
+		switch v & 0x0F {
+		case meterSetOverload:
+			return callback(true)
+		case meterClearOverload:
+			return callback(false)
+		}
+		return nil
+	})
+}
@@ -1,6 +1,7 @@
 package devices
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -20,20 +21,45 @@ func init() {
 	midiOutLog = logging.Get(logging.MIDI_OUT)
 }
 
+// portPollInterval is how often a running MidiDevice checks that its ports are still present.
+const portPollInterval = time.Second
+
 // MidiDevice represents a generic MIDI device and allows registering effects for various messages the device may receive.
 type MidiDevice struct {
-	inPort  drivers.In
-	outPort drivers.Out
+	portMu        sync.RWMutex
+	inPort        drivers.In
+	outPort       drivers.Out
+	inName        string
+	outName       string
+	virtualName   string
+	stopListening func()
+	lookup        PortLookup
 
 	SysEx *sysEx
 
 	mu         sync.RWMutex
 	cc         map[*cC]struct{}
+	cc14       map[*cC14]struct{}
+	params     map[*parameter]struct{}
 	pitchBend  map[*pitchBend]struct{}
 	noteOn     map[*noteOn]struct{}
 	noteOff    map[*noteOff]struct{}
 	aftertouch map[*afterTouch]struct{}
+	polyAfter  map[*polyAfterTouch]struct{}
+	program    map[*programChange]struct{}
+	songPos    map[*songPosition]struct{}
+	mtc        map[*mtcQuarterFrame]struct{}
+	realTime   map[*realTime]struct{}
 	sysex      map[*sysExMatch]struct{}
+
+	// hiResMu guards the running 14-bit controller and NRPN/RPN state, which incoming messages update while
+	// holding mu only for reading.
+	hiResMu     sync.Mutex
+	paramSel    [16]paramSelection
+	paramValues map[paramKey]uint16
+
+	// hiResOutMu keeps multi-message 14-bit and parameter sends from interleaving.
+	hiResOutMu sync.Mutex
 }
 
 func (f *MidiDevice) CC(channel, controller uint8) *cC {
@@ -66,6 +92,7 @@ func (f *MidiDevice) Note(channel, key uint8) *note {
 	}
 }
 
+// Aftertouch returns an endpoint for channel pressure on the given channel. For per-key pressure, see PolyAftertouch.
 func (f *MidiDevice) Aftertouch(channel uint8) *afterTouch {
 	return &afterTouch{
 		device:  f,
@@ -73,6 +100,73 @@ func (f *MidiDevice) Aftertouch(channel uint8) *afterTouch {
 	}
 }
 
+// PolyAftertouch returns an endpoint for polyphonic key pressure on the given channel and key.
+func (f *MidiDevice) PolyAftertouch(channel, key uint8) *polyAfterTouch {
+	return &polyAfterTouch{
+		device:  f,
+		channel: channel,
+		key:     key,
+	}
+}
+
+// ProgramChange returns an endpoint for program changes on the given channel.
+func (f *MidiDevice) ProgramChange(channel uint8) *programChange {
+	return &programChange{
+		device:  f,
+		channel: channel,
+	}
+}
+
+// SongPosition returns an endpoint for Song Position Pointer messages, in MIDI beats (sixteenth notes).
+func (f *MidiDevice) SongPosition() *songPosition {
+	return &songPosition{
+		device: f,
+	}
+}
+
+// MTC returns an endpoint for MIDI Time Code quarter-frame messages.
+func (f *MidiDevice) MTC() *mtcQuarterFrame {
+	return &mtcQuarterFrame{
+		device: f,
+	}
+}
+
+// Clock returns an endpoint for MIDI timing clock messages, sent 24 times per quarter note.
+func (f *MidiDevice) Clock() *realTime {
+	return &realTime{
+		device:  f,
+		msgType: midi.TimingClockMsg,
+		msg:     midi.TimingClock(),
+	}
+}
+
+// Start returns an endpoint for MIDI Start messages.
+func (f *MidiDevice) Start() *realTime {
+	return &realTime{
+		device:  f,
+		msgType: midi.StartMsg,
+		msg:     midi.Start(),
+	}
+}
+
+// Stop returns an endpoint for MIDI Stop messages.
+func (f *MidiDevice) Stop() *realTime {
+	return &realTime{
+		device:  f,
+		msgType: midi.StopMsg,
+		msg:     midi.Stop(),
+	}
+}
+
+// Continue returns an endpoint for MIDI Continue messages.
+func (f *MidiDevice) Continue() *realTime {
+	return &realTime{
+		device:  f,
+		msgType: midi.ContinueMsg,
+		msg:     midi.Continue(),
+	}
+}
+
 type cC struct {
 	device     *MidiDevice
 	channel    uint8
@@ -94,7 +188,7 @@ func (ep *cC) Bind(callback func(value uint8) error) func() {
 
 func (ep *cC) Set(value uint8) error {
 	midiOutLog.Debug("Sending Control Change", "channel", ep.channel, "controller", ep.controller, "value", value)
-	return ep.device.outPort.Send(midi.ControlChange(ep.channel, ep.controller, value))
+	return ep.device.send(midi.ControlChange(ep.channel, ep.controller, value))
 }
 
 type pitchBend struct {
@@ -117,7 +211,7 @@ func (ep *pitchBend) Bind(callback func(uint16) error) func() {
 
 func (ep *pitchBend) Set(value uint16) error {
 	midiOutLog.Debug("Sending Pitch Bend", "channel", ep.channel, "value", value)
-	return ep.device.outPort.Send(midi.Pitchbend(ep.channel, int16(value-0x2000)))
+	return ep.device.send(midi.Pitchbend(ep.channel, int16(value-0x2000)))
 }
 
 type note struct {
@@ -146,7 +240,7 @@ func (ep *noteOn) Bind(callback func(uint8) error) func() {
 
 func (ep *noteOn) Set(velocity uint8) error {
 	midiOutLog.Debug("Sending Note On", "channel", ep.channel, "key", ep.key, "velocity", velocity)
-	return ep.device.outPort.Send(midi.NoteOn(ep.channel, ep.key, velocity))
+	return ep.device.send(midi.NoteOn(ep.channel, ep.key, velocity))
 }
 
 type noteOff struct {
@@ -170,7 +264,7 @@ func (ep *noteOff) Bind(callback func() error) func() {
 
 func (ep *noteOff) Set() error {
 	midiOutLog.Debug("Sending Note Off", "channel", ep.channel, "key", ep.key)
-	return ep.device.outPort.Send(midi.NoteOff(ep.channel, ep.key))
+	return ep.device.send(midi.NoteOff(ep.channel, ep.key))
 }
 
 type afterTouch struct {
@@ -193,14 +287,142 @@ func (ep *afterTouch) Bind(callback func(uint8) error) func() {
 
 func (ep *afterTouch) Set(value uint8) error {
 	midiOutLog.Debug("Sending After Touch", "channel", ep.channel, "value", value)
-	return ep.device.outPort.Send(midi.AfterTouch(ep.channel, value))
+	return ep.device.send(midi.AfterTouch(ep.channel, value))
+}
+
+type polyAfterTouch struct {
+	device   *MidiDevice
+	channel  uint8
+	key      uint8
+	callback func(uint8) error
+}
+
+func (ep *polyAfterTouch) Bind(callback func(uint8) error) func() {
+	ep.callback = callback
+	ep.device.mu.Lock()
+	ep.device.polyAfter[ep] = struct{}{}
+	ep.device.mu.Unlock()
+	return func() {
+		ep.device.mu.Lock()
+		delete(ep.device.polyAfter, ep)
+		ep.device.mu.Unlock()
+	}
+}
+
+func (ep *polyAfterTouch) Set(pressure uint8) error {
+	midiOutLog.Debug("Sending Poly After Touch", "channel", ep.channel, "key", ep.key, "pressure", pressure)
+	return ep.device.send(midi.PolyAfterTouch(ep.channel, ep.key, pressure))
+}
+
+type programChange struct {
+	device   *MidiDevice
+	channel  uint8
+	callback func(uint8) error
+}
+
+func (ep *programChange) Bind(callback func(uint8) error) func() {
+	ep.callback = callback
+	ep.device.mu.Lock()
+	ep.device.program[ep] = struct{}{}
+	ep.device.mu.Unlock()
+	return func() {
+		ep.device.mu.Lock()
+		delete(ep.device.program, ep)
+		ep.device.mu.Unlock()
+	}
+}
+
+func (ep *programChange) Set(program uint8) error {
+	midiOutLog.Debug("Sending Program Change", "channel", ep.channel, "program", program)
+	return ep.device.send(midi.ProgramChange(ep.channel, program))
+}
+
+type songPosition struct {
+	device   *MidiDevice
+	callback func(uint16) error
+}
+
+func (ep *songPosition) Bind(callback func(uint16) error) func() {
+	ep.callback = callback
+	ep.device.mu.Lock()
+	ep.device.songPos[ep] = struct{}{}
+	ep.device.mu.Unlock()
+	return func() {
+		ep.device.mu.Lock()
+		delete(ep.device.songPos, ep)
+		ep.device.mu.Unlock()
+	}
+}
+
+func (ep *songPosition) Set(beats uint16) error {
+	midiOutLog.Debug("Sending Song Position Pointer", "beats", beats)
+	return ep.device.send(midi.SPP(beats))
+}
+
+// mtcQuarterFrame carries the raw quarter-frame data byte: the high nibble selects which piece of the timecode
+// is being sent and the low nibble holds its value.
+type mtcQuarterFrame struct {
+	device   *MidiDevice
+	callback func(uint8) error
+}
+
+func (ep *mtcQuarterFrame) Bind(callback func(uint8) error) func() {
+	ep.callback = callback
+	ep.device.mu.Lock()
+	ep.device.mtc[ep] = struct{}{}
+	ep.device.mu.Unlock()
+	return func() {
+		ep.device.mu.Lock()
+		delete(ep.device.mtc, ep)
+		ep.device.mu.Unlock()
+	}
+}
+
+func (ep *mtcQuarterFrame) Set(quarterFrame uint8) error {
+	midiOutLog.Debug("Sending MTC Quarter Frame", "data", quarterFrame)
+	return ep.device.send(midi.MTC(quarterFrame))
+}
+
+// realTime is an endpoint for a single-byte system real-time message such as Clock or Start.
+type realTime struct {
+	device   *MidiDevice
+	msgType  midi.Type
+	msg      midi.Message
+	callback func() error
+}
+
+func (ep *realTime) Bind(callback func() error) func() {
+	ep.callback = callback
+	ep.device.mu.Lock()
+	ep.device.realTime[ep] = struct{}{}
+	ep.device.mu.Unlock()
+	return func() {
+		ep.device.mu.Lock()
+		delete(ep.device.realTime, ep)
+		ep.device.mu.Unlock()
+	}
+}
+
+func (ep *realTime) Set() error {
+	if ep.msgType != midi.TimingClockMsg {
+		midiOutLog.Debug("Sending Real Time", "type", ep.msgType)
+	}
+	return ep.device.send(ep.msg)
 }
 
 type sysEx struct {
 	device *MidiDevice
 }
 
+// Match returns an endpoint for SysEx messages beginning with exactly the given bytes.
 func (ep *sysEx) Match(pattern []byte) *sysExMatch {
+	return ep.MatchPattern(exactSysExPattern(pattern))
+}
+
+// MatchPattern returns an endpoint for SysEx messages matching the given pattern.
+//
+// Use this instead of Match when the message embeds variable bytes such as device IDs or channel numbers.
+func (ep *sysEx) MatchPattern(pattern *SysExPattern) *sysExMatch {
 	return &sysExMatch{
 		pattern: pattern,
 		device:  ep.device,
@@ -222,20 +444,29 @@ func byteSliceToHexLiteral(b []byte) string {
 
 func (ep *sysEx) Set(value []byte) error {
 	midiOutLog.Debug("Sending SysEx", "bytes", byteSliceToHexLiteral(value))
-	return ep.device.outPort.Send(value)
+	return ep.device.send(value)
 }
 
 func (ep *sysEx) SetSilent(value []byte) error {
-	return ep.device.outPort.Send(value)
+	return ep.device.send(value)
 }
 
 type sysExMatch struct {
-	pattern  []byte
+	pattern  *SysExPattern
 	device   *MidiDevice
-	callback func([]byte) error
+	callback func([]byte, SysExCaptures) error
 }
 
+// Bind specifies the callback to run with the raw bytes of each matching SysEx message.
 func (ep *sysExMatch) Bind(callback func([]byte) error) func() {
+	return ep.BindCaptures(func(data []byte, _ SysExCaptures) error {
+		return callback(data)
+	})
+}
+
+// BindCaptures specifies the callback to run for each matching SysEx message. The callback receives the raw
+// bytes along with the values of any named fields in the pattern.
+func (ep *sysExMatch) BindCaptures(callback func([]byte, SysExCaptures) error) func() {
 	ep.callback = callback
 	ep.device.mu.Lock()
 	ep.device.sysex[ep] = struct{}{}
@@ -251,171 +482,387 @@ func NewMidiDevice(inPort drivers.In, outPort drivers.Out) *MidiDevice {
 	d := &MidiDevice{
 		inPort:  inPort,
 		outPort: outPort,
+		inName:  inPort.String(),
+		outName: outPort.String(),
 		SysEx: &sysEx{
 			device: &MidiDevice{},
 		},
 		cc:         make(map[*cC]struct{}),
+		cc14:       make(map[*cC14]struct{}),
+		params:     make(map[*parameter]struct{}),
 		pitchBend:  make(map[*pitchBend]struct{}),
 		noteOn:     make(map[*noteOn]struct{}),
 		noteOff:    make(map[*noteOff]struct{}),
 		aftertouch: make(map[*afterTouch]struct{}),
+		polyAfter:  make(map[*polyAfterTouch]struct{}),
+		program:    make(map[*programChange]struct{}),
+		songPos:    make(map[*songPosition]struct{}),
+		mtc:        make(map[*mtcQuarterFrame]struct{}),
+		realTime:   make(map[*realTime]struct{}),
 		sysex:      make(map[*sysExMatch]struct{}),
+
+		paramValues: make(map[paramKey]uint16),
 	}
 	d.SysEx = &sysEx{device: d}
+	if drivers.Get() != nil {
+		d.lookup = driverLookup{}
+	}
 	return d
 }
 
-// Run starts this device and causes it to listen and respond to incoming MIDI messages.
+// PortLookup finds MIDI ports by name. A running MidiDevice uses it to notice that its ports have disappeared
+// and to find them again when they return.
+type PortLookup interface {
+	FindIn(name string) (drivers.In, error)
+	FindOut(name string) (drivers.Out, error)
+}
+
+// driverLookup finds ports through the registered gomidi driver.
+type driverLookup struct{}
+
+func (driverLookup) FindIn(name string) (drivers.In, error) {
+	return midi.FindInPort(name)
+}
+
+func (driverLookup) FindOut(name string) (drivers.Out, error) {
+	return midi.FindOutPort(name)
+}
+
+// SetPortLookup replaces how this device finds its ports while running. By default ports are looked up through
+// the registered driver; devices created without one assume their ports never disappear. SetPortLookup must be
+// called before Run.
+func (d *MidiDevice) SetPortLookup(lookup PortLookup) {
+	d.lookup = lookup
+}
+
+// Run opens this device's ports and causes it to listen and respond to incoming MIDI messages until ctx is
+// cancelled, at which point the ports are closed and Run returns.
 //
 // For any message with an effect registered, that effect will be run each time such a message is received.
+//
+// While running, the device watches the driver's port list. If either port disappears (e.g. a USB surface is
+// unplugged or power-cycled) the ports are closed and the device waits for a port with the same name to
+// reappear, then reopens it. Bindings are preserved across reconnections.
+func (d *MidiDevice) Run(ctx context.Context) error {
+	midiInLog.Info("Starting MIDI device", "inPort", d.inName, "outPort", d.outName)
+	if err := d.open(); err != nil {
+		return err
+	}
+	defer d.close()
+
+	ticker := time.NewTicker(portPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			midiInLog.Info("Stopping MIDI device", "inPort", d.inName, "outPort", d.outName)
+			return nil
+		case <-ticker.C:
+			if d.IsConnected() {
+				if !d.portsPresent() {
+					midiInLog.Warn("MIDI ports disappeared; waiting for reconnection", "inPort", d.inName, "outPort", d.outName)
+					d.close()
+				}
+				continue
+			}
+			if err := d.reconnect(); err != nil {
+				midiInLog.Debug("MIDI ports not yet available", "inPort", d.inName, "outPort", d.outName, "err", err)
+				continue
+			}
+			midiInLog.Info("Reconnected MIDI device", "inPort", d.inName, "outPort", d.outName)
+		}
+	}
+}
+
+// IsConnected reports whether this device's ports are currently open and listening.
+func (d *MidiDevice) IsConnected() bool {
+	d.portMu.RLock()
+	defer d.portMu.RUnlock()
+	return d.stopListening != nil
+}
+
+// send writes raw MIDI bytes to the current output port.
+func (d *MidiDevice) send(data []byte) error {
+	d.portMu.RLock()
+	defer d.portMu.RUnlock()
+	return d.outPort.Send(data)
+}
+
+// open opens both ports and begins listening on the input port.
+func (d *MidiDevice) open() error {
+	d.portMu.Lock()
+	defer d.portMu.Unlock()
+	if d.IsVirtual() {
+		if err := d.openVirtual(); err != nil {
+			return err
+		}
+	} else {
+		if err := d.inPort.Open(); err != nil {
+			return fmt.Errorf("failed to open MIDI input port %s: %w", d.inName, err)
+		}
+		if err := d.outPort.Open(); err != nil {
+			d.closeIn()
+			return fmt.Errorf("failed to open MIDI output port %s: %w", d.outName, err)
+		}
+	}
+	stop, err := midi.ListenTo(d.inPort, d.handle, midi.UseSysEx(), midi.UseTimeCode())
+	if err != nil {
+		d.closeIn()
+		d.closeOut()
+		return fmt.Errorf("failed to listen on MIDI input port %s: %w", d.inName, err)
+	}
+	d.stopListening = stop
+	return nil
+}
 
-func (d *MidiDevice) Run() {
-	midiInLog.Info("Starting MIDI device", "inPort", d.inPort.String(), "outPort", d.outPort.String())
-	go d.run()
+// openVirtual recreates this device's virtual ports if they have been closed. Virtual ports are opened when they
+// are created and cannot be reopened through drivers.Port.Open.
+func (d *MidiDevice) openVirtual() error {
+	if d.inPort.IsOpen() && d.outPort.IsOpen() {
+		return nil
+	}
+	d.closeIn()
+	d.closeOut()
+	in, out, err := openVirtualPorts(d.virtualName)
+	if err != nil {
+		return err
+	}
+	d.inPort, d.outPort = in, out
+	return nil
+}
+
+// close stops listening and closes both ports. It is safe to call on a device that is not connected.
+func (d *MidiDevice) close() {
+	d.portMu.Lock()
+	defer d.portMu.Unlock()
+	if d.stopListening == nil {
+		return
+	}
+	d.stopListening()
+	d.stopListening = nil
+	d.closeIn()
+	d.closeOut()
 }
 
-func (f *MidiDevice) run() {
-	if err := f.inPort.Open(); err != nil {
-		panic(fmt.Errorf("failed to open MIDI input port %s: %w", f.inPort.String(), err))
+func (d *MidiDevice) closeIn() {
+	if err := d.inPort.Close(); err != nil {
+		midiInLog.Error("failed to close MIDI input port:", "port", d.inName, "err", err)
 	}
-	defer func() {
-		if err := f.inPort.Close(); err != nil {
-			midiInLog.Error("failed to close MIDI input port:", "err", err)
+}
+
+func (d *MidiDevice) closeOut() {
+	if err := d.outPort.Close(); err != nil {
+		midiOutLog.Error("failed to close MIDI output port:", "port", d.outName, "err", err)
+	}
+}
+
+// portsPresent reports whether this device's ports can still be found by name.
+//
+// Virtual ports and devices without a port lookup are assumed to always be present.
+func (d *MidiDevice) portsPresent() bool {
+	if d.IsVirtual() || d.lookup == nil {
+		return true
+	}
+	if _, err := d.lookup.FindIn(d.inName); err != nil {
+		return false
+	}
+	_, err := d.lookup.FindOut(d.outName)
+	return err == nil
+}
+
+// reconnect looks up this device's ports by name and reopens them.
+func (d *MidiDevice) reconnect() error {
+	if !d.IsVirtual() && d.lookup != nil {
+		in, err := d.lookup.FindIn(d.inName)
+		if err != nil {
+			return err
+		}
+		out, err := d.lookup.FindOut(d.outName)
+		if err != nil {
+			return err
 		}
-	}()
-	if err := f.outPort.Open(); err != nil {
-		panic(fmt.Errorf("failed to open MIDI output port %s: %w", f.outPort.String(), err))
+		d.portMu.Lock()
+		d.inPort, d.outPort = in, out
+		d.portMu.Unlock()
 	}
-	defer func() {
-		if err := f.outPort.Close(); err != nil {
-			midiOutLog.Error("failed to close MIDI output port:", "err", err)
+	return d.open()
+}
+
+// handle dispatches a single incoming MIDI message to all matching bindings.
+func (f *MidiDevice) handle(msg midi.Message, timestampms int32) {
+	switch msg.Type() {
+	case midi.ControlChangeMsg:
+		var channel, control, value uint8
+		if ok := msg.GetControlChange(&channel, &control, &value); !ok {
+			midiInLog.Error("failed to parse Control Change message:", "msg", msg)
+			return
 		}
-	}()
-
-	var err error
-	var stop func()
-
-	stop, err = midi.ListenTo(f.inPort, func(msg midi.Message, timestampms int32) {
-		switch msg.Type() {
-		case midi.ControlChangeMsg:
-			var channel, control, value uint8
-			if ok := msg.GetControlChange(&channel, &control, &value); !ok {
-				midiInLog.Error("failed to parse Control Change message:", "err", err)
-				return
-			}
-			midiInLog.Debug("received Control Change message", "channel", channel, "control", control, "value", value, "timestamp", timestampms)
-			f.mu.RLock()
-			for cc := range f.cc {
-				if cc.channel == channel && cc.controller == control {
-					if err := cc.callback(value); err != nil {
-						midiInLog.Error("failed to process Control Change:", "err", err)
-					}
+		midiInLog.Debug("received Control Change message", "channel", channel, "control", control, "value", value, "timestamp", timestampms)
+		f.mu.RLock()
+		for cc := range f.cc {
+			if cc.channel == channel && cc.controller == control {
+				if err := cc.callback(value); err != nil {
+					midiInLog.Error("failed to process Control Change:", "err", err)
 				}
 			}
-			f.mu.RUnlock()
-		case midi.PitchBendMsg:
-			var channel uint8
-			var relative int16
-			var absolute uint16
-			if ok := msg.GetPitchBend(&channel, &relative, &absolute); !ok {
-				midiInLog.Error("failed to parse Pitch Bend message:", "err", err)
-				return
+		}
+		f.handleHiResCC(channel, control, value)
+		f.mu.RUnlock()
+	case midi.PitchBendMsg:
+		var channel uint8
+		var relative int16
+		var absolute uint16
+		if ok := msg.GetPitchBend(&channel, &relative, &absolute); !ok {
+			midiInLog.Error("failed to parse Pitch Bend message:", "msg", msg)
+			return
+		}
+		midiInLog.Debug("received Pitch Bend message", "channel", channel, "absolute", absolute, "timestamp", timestampms)
+		f.mu.RLock()
+		for pitchbend := range f.pitchBend {
+			if pitchbend.channel == channel {
+				if err := pitchbend.callback(absolute); err != nil {
+					midiInLog.Error("failed to process Pitch Bend:", "err", err)
+				}
 			}
-			midiInLog.Debug("received Pitch Bend message", "channel", channel, "absolute", absolute, "timestamp", timestampms)
-			f.mu.RLock()
-			for pitchbend := range f.pitchBend {
-				if pitchbend.channel == channel {
-					if err := pitchbend.callback(absolute); err != nil {
-						midiInLog.Error("failed to process Pitch Bend:", "err", err)
-					}
+		}
+		f.mu.RUnlock()
+	case midi.NoteOnMsg:
+		var channel, key, velocity uint8
+		if ok := msg.GetNoteOn(&channel, &key, &velocity); !ok {
+			midiInLog.Error("failed to parse Note On message:", "msg", msg)
+			return
+		}
+		midiInLog.Debug("received Note On message", "channel", channel, "key", key, "velocity", velocity, "timestamp", timestampms)
+		f.mu.RLock()
+		for note := range f.noteOn {
+			if note.key == key && note.channel == channel {
+				if err := note.callback(velocity); err != nil {
+					midiInLog.Error("failed to process Note On:", "err", err)
 				}
 			}
-			f.mu.RUnlock()
-		case midi.NoteOnMsg:
-			var channel, key, velocity uint8
-			if ok := msg.GetNoteOn(&channel, &key, &velocity); !ok {
-				midiInLog.Error("failed to parse Note On message:", "err", err)
-				return
+		}
+		f.mu.RUnlock()
+	case midi.NoteOffMsg:
+		var channel, key, velocity uint8
+		if ok := msg.GetNoteOff(&channel, &key, &velocity); !ok {
+			midiInLog.Error("failed to parse Note Off message:", "msg", msg)
+			return
+		}
+		midiInLog.Debug("received Note Off message", "channel", channel, "key", key, "velocity", velocity, "timestamp", timestampms)
+		f.mu.RLock()
+		for note := range f.noteOff {
+			if note.key == key && note.channel == channel {
+				if err := note.callback(); err != nil {
+					midiInLog.Error("failed to process Note Off:", "err", err)
+				}
 			}
-			midiInLog.Debug("received Note On message", "channel", channel, "key", key, "velocity", velocity, "timestamp", timestampms)
-			f.mu.RLock()
-			for note := range f.noteOn {
-				if note.key == key && note.channel == channel {
-					if err := note.callback(velocity); err != nil {
-						midiInLog.Error("failed to process Note On:", "err", err)
-					}
+		}
+		f.mu.RUnlock()
+	case midi.AfterTouchMsg:
+		var channel, pressure uint8
+		if ok := msg.GetAfterTouch(&channel, &pressure); !ok {
+			midiInLog.Error("failed to parse After Touch message:", "msg", msg)
+			return
+		}
+		midiInLog.Debug("received After Touch message", "channel", channel, "pressure", pressure, "timestamp", timestampms)
+		f.mu.RLock()
+		for aftertouch := range f.aftertouch {
+			if aftertouch.channel == channel {
+				if err := aftertouch.callback(pressure); err != nil {
+					midiInLog.Error("failed to process After Touch:", "err", err)
 				}
 			}
-			f.mu.RUnlock()
-		case midi.NoteOffMsg:
-			var channel, key, velocity uint8
-			if ok := msg.GetNoteOff(&channel, &key, &velocity); !ok {
-				midiInLog.Error("failed to parse Note Off message:", "err", err)
-				return
+		}
+		f.mu.RUnlock()
+	case midi.PolyAfterTouchMsg:
+		var channel, key, pressure uint8
+		if ok := msg.GetPolyAfterTouch(&channel, &key, &pressure); !ok {
+			midiInLog.Error("failed to parse Poly After Touch message:", "msg", msg)
+			return
+		}
+		midiInLog.Debug("received Poly After Touch message", "channel", channel, "key", key, "pressure", pressure, "timestamp", timestampms)
+		f.mu.RLock()
+		for aftertouch := range f.polyAfter {
+			if aftertouch.channel == channel && aftertouch.key == key {
+				if err := aftertouch.callback(pressure); err != nil {
+					midiInLog.Error("failed to process Poly After Touch:", "err", err)
+				}
 			}
-			midiInLog.Debug("received Note Off message", "channel", channel, "key", key, "velocity", velocity, "timestamp", timestampms)
-			f.mu.RLock()
-			for note := range f.noteOff {
-				if note.key == key && note.channel == channel {
-					if err := note.callback(); err != nil {
-						midiInLog.Error("failed to process Note Off:", "err", err)
-					}
+		}
+		f.mu.RUnlock()
+	case midi.ProgramChangeMsg:
+		var channel, program uint8
+		if ok := msg.GetProgramChange(&channel, &program); !ok {
+			midiInLog.Error("failed to parse Program Change message:", "msg", msg)
+			return
+		}
+		midiInLog.Debug("received Program Change message", "channel", channel, "program", program, "timestamp", timestampms)
+		f.mu.RLock()
+		for pc := range f.program {
+			if pc.channel == channel {
+				if err := pc.callback(program); err != nil {
+					midiInLog.Error("failed to process Program Change:", "err", err)
 				}
 			}
-			f.mu.RUnlock()
-		case midi.AfterTouchMsg:
-			var channel, pressure uint8
-			if ok := msg.GetAfterTouch(&channel, &pressure); !ok {
-				midiInLog.Error("failed to parse After Touch message:", "err", err)
-				return
+		}
+		f.mu.RUnlock()
+	case midi.SPPMsg:
+		var beats uint16
+		if ok := msg.GetSPP(&beats); !ok {
+			midiInLog.Error("failed to parse Song Position Pointer message:", "msg", msg)
+			return
+		}
+		midiInLog.Debug("received Song Position Pointer message", "beats", beats, "timestamp", timestampms)
+		f.mu.RLock()
+		for spp := range f.songPos {
+			if err := spp.callback(beats); err != nil {
+				midiInLog.Error("failed to process Song Position Pointer:", "err", err)
 			}
-			midiInLog.Debug("received After Touch message", "channel", channel, "pressure", pressure, "timestamp", timestampms)
-			f.mu.RLock()
-			for aftertouch := range f.aftertouch {
-				if aftertouch.channel == channel {
-					if err := aftertouch.callback(pressure); err != nil {
-						midiInLog.Error("failed to process After Touch:", "err", err)
-					}
-				}
+		}
+		f.mu.RUnlock()
+	case midi.MTCMsg:
+		var quarterFrame uint8
+		if ok := msg.GetMTC(&quarterFrame); !ok {
+			midiInLog.Error("failed to parse MTC message:", "msg", msg)
+			return
+		}
+		f.mu.RLock()
+		for mtc := range f.mtc {
+			if err := mtc.callback(quarterFrame); err != nil {
+				midiInLog.Error("failed to process MTC:", "err", err)
 			}
-			f.mu.RUnlock()
-		case midi.SysExMsg:
-			var data []byte
-			if ok := msg.GetSysEx(&data); !ok {
-				midiInLog.Error("failed to parse SysEx message:", "err", err)
-				return
+		}
+		f.mu.RUnlock()
+	case midi.TimingClockMsg, midi.StartMsg, midi.StopMsg, midi.ContinueMsg:
+		// Clock arrives 24 times per quarter note, so only the transport messages are worth logging.
+		if msg.Type() != midi.TimingClockMsg {
+			midiInLog.Debug("received Real Time message", "type", msg.Type(), "timestamp", timestampms)
+		}
+		f.mu.RLock()
+		for rt := range f.realTime {
+			if rt.msgType == msg.Type() {
+				if err := rt.callback(); err != nil {
+					midiInLog.Error("failed to process Real Time:", "type", msg.Type(), "err", err)
+				}
 			}
-			midiInLog.Debug("received SysEx message", "data", data, "timestamp", timestampms)
-			f.mu.RLock()
-			for sysex := range f.sysex {
-				// Check if the message matches the pattern
-				//
-				// NOTE: currently, we check for directly matching patterns; this won't work with variable arguments embedded into the data
-				if len(data) >= len(sysex.pattern) {
-					matches := true
-					for i, b := range sysex.pattern {
-						if data[i] != b {
-							matches = false
-							break
-						}
-					}
-					if matches {
-						if err := sysex.callback(data); err != nil {
-							midiInLog.Error("failed to process SysEx:", "err", err)
-						}
-					}
+		}
+		f.mu.RUnlock()
+	case midi.SysExMsg:
+		var data []byte
+		if ok := msg.GetSysEx(&data); !ok {
+			midiInLog.Error("failed to parse SysEx message:", "msg", msg)
+			return
+		}
+		midiInLog.Debug("received SysEx message", "data", data, "timestamp", timestampms)
+		f.mu.RLock()
+		for sysex := range f.sysex {
+			if captures, ok := sysex.pattern.Match(data); ok {
+				if err := sysex.callback(data, captures); err != nil {
+					midiInLog.Error("failed to process SysEx:", "pattern", sysex.pattern, "err", err)
 				}
 			}
-			f.mu.RUnlock()
 		}
-	}, midi.UseSysEx())
-	if err != nil {
-		midiInLog.Error("ERROR: %s\n", "err", err)
-		return
+		f.mu.RUnlock()
 	}
-
-	// TODO: put this in a goroutine instead of sleeping!
-	time.Sleep(time.Second * 1000)
-
-	stop()
 }
@@ -0,0 +1,306 @@
+package devices
+
+import (
+	"fmt"
+
+	midi "gitlab.com/gomidi/midi/v2"
+)
+
+// Controller numbers used to select and edit registered and non-registered parameters.
+const (
+	ccDataEntryMSB uint8 = 6
+	ccDataEntryLSB uint8 = 38
+	ccDataInc      uint8 = 96
+	ccDataDec      uint8 = 97
+	ccNRPNLSB      uint8 = 98
+	ccNRPNMSB      uint8 = 99
+	ccRPNLSB       uint8 = 100
+	ccRPNMSB       uint8 = 101
+)
+
+// max14Bit is the largest value representable by a 14-bit MSB/LSB pair.
+const max14Bit uint16 = 0x3FFF
+
+// maxCC14MSB is the highest controller number that has an LSB partner.
+const maxCC14MSB uint8 = 31
+
+type paramKind uint8
+
+const (
+	paramNone paramKind = iota
+	paramNRPN
+	paramRPN
+)
+
+func (k paramKind) String() string {
+	switch k {
+	case paramNRPN:
+		return "NRPN"
+	case paramRPN:
+		return "RPN"
+	default:
+		return "none"
+	}
+}
+
+type paramKey struct {
+	kind    paramKind
+	channel uint8
+	number  uint16
+}
+
+// paramSelection tracks the parameter currently selected on a channel along with its partially received value.
+type paramSelection struct {
+	kind     paramKind
+	msb, lsb uint8
+	valueMSB uint8
+}
+
+func (s *paramSelection) key(channel uint8) paramKey {
+	return paramKey{s.kind, channel, uint16(s.msb)<<7 | uint16(s.lsb)}
+}
+
+// CC14 returns an endpoint for a 14-bit controller formed by the MSB controller msb (0-31) and its LSB partner
+// msb+32.
+//
+// Incoming values are delivered once the LSB arrives; the MSB alone only latches the coarse half of the value.
+// Controllers above 31 have no LSB partner and are rejected by Bind and Set.
+func (f *MidiDevice) CC14(channel, msb uint8) *cC14 {
+	return &cC14{
+		device:  f,
+		channel: channel,
+		msb:     msb,
+	}
+}
+
+// NRPN returns an endpoint for the given non-registered parameter number (0-16383) on the given channel.
+func (f *MidiDevice) NRPN(channel uint8, number uint16) *parameter {
+	return &parameter{
+		device:  f,
+		kind:    paramNRPN,
+		channel: channel,
+		number:  number,
+	}
+}
+
+// RPN returns an endpoint for the given registered parameter number (0-16383) on the given channel.
+func (f *MidiDevice) RPN(channel uint8, number uint16) *parameter {
+	return &parameter{
+		device:  f,
+		kind:    paramRPN,
+		channel: channel,
+		number:  number,
+	}
+}
+
+type cC14 struct {
+	device   *MidiDevice
+	channel  uint8
+	msb      uint8
+	msbValue uint8 // guarded by device.hiResMu
+	callback func(uint16) error
+}
+
+func (ep *cC14) validate() error {
+	if ep.msb > maxCC14MSB {
+		return fmt.Errorf("invalid controller %d: 14-bit controllers must be at most %d", ep.msb, maxCC14MSB)
+	}
+	return nil
+}
+
+func (ep *cC14) Bind(callback func(uint16) error) func() {
+	if err := ep.validate(); err != nil {
+		midiInLog.Error("failed to bind 14-bit Control Change:", "err", err)
+		return func() {}
+	}
+	ep.callback = callback
+	ep.device.mu.Lock()
+	ep.device.cc14[ep] = struct{}{}
+	ep.device.mu.Unlock()
+	return func() {
+		ep.device.mu.Lock()
+		delete(ep.device.cc14, ep)
+		ep.device.mu.Unlock()
+	}
+}
+
+// Set sends value as an MSB/LSB pair of Control Change messages.
+func (ep *cC14) Set(value uint16) error {
+	if err := ep.validate(); err != nil {
+		return err
+	}
+	if value > max14Bit {
+		return fmt.Errorf("invalid value %d: 14-bit controller values must be at most %d", value, max14Bit)
+	}
+	midiOutLog.Debug("Sending 14-bit Control Change", "channel", ep.channel, "controller", ep.msb, "value", value)
+	ep.device.hiResOutMu.Lock()
+	defer ep.device.hiResOutMu.Unlock()
+	if err := ep.device.send(midi.ControlChange(ep.channel, ep.msb, uint8(value>>7))); err != nil {
+		return err
+	}
+	return ep.device.send(midi.ControlChange(ep.channel, ep.msb+32, uint8(value&0x7F)))
+}
+
+type parameter struct {
+	device   *MidiDevice
+	kind     paramKind
+	channel  uint8
+	number   uint16
+	callback func(uint16) error
+}
+
+func (ep *parameter) key() paramKey {
+	return paramKey{ep.kind, ep.channel, ep.number}
+}
+
+func (ep *parameter) Bind(callback func(uint16) error) func() {
+	ep.callback = callback
+	ep.device.mu.Lock()
+	ep.device.params[ep] = struct{}{}
+	ep.device.mu.Unlock()
+	return func() {
+		ep.device.mu.Lock()
+		delete(ep.device.params, ep)
+		ep.device.mu.Unlock()
+	}
+}
+
+// Set selects this parameter and sends value using Data Entry MSB and LSB. Concurrent sets on the same device are
+// serialized so that data entry always follows its own selection.
+func (ep *parameter) Set(value uint16) error {
+	if ep.number > max14Bit {
+		return fmt.Errorf("invalid %s number %d: must be at most %d", ep.kind, ep.number, max14Bit)
+	}
+	if value > max14Bit {
+		return fmt.Errorf("invalid value %d: %s values must be at most %d", value, ep.kind, max14Bit)
+	}
+	midiOutLog.Debug("Sending "+ep.kind.String(), "channel", ep.channel, "number", ep.number, "value", value)
+	selMSB, selLSB := ccNRPNMSB, ccNRPNLSB
+	if ep.kind == paramRPN {
+		selMSB, selLSB = ccRPNMSB, ccRPNLSB
+	}
+	ep.device.hiResOutMu.Lock()
+	defer ep.device.hiResOutMu.Unlock()
+	for _, msg := range []midi.Message{
+		midi.ControlChange(ep.channel, selMSB, uint8(ep.number>>7)),
+		midi.ControlChange(ep.channel, selLSB, uint8(ep.number&0x7F)),
+		midi.ControlChange(ep.channel, ccDataEntryMSB, uint8(value>>7)),
+		midi.ControlChange(ep.channel, ccDataEntryLSB, uint8(value&0x7F)),
+	} {
+		if err := ep.device.send(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hiResUpdate is a completed 14-bit controller or parameter value waiting for its callbacks to run.
+type hiResUpdate struct {
+	cc    *cC14
+	param paramKey
+	value uint16
+}
+
+// handleHiResCC updates 14-bit controller and parameter state for an incoming Control Change and runs any
+// callbacks whose value is now complete. The caller must hold f.mu for reading.
+func (f *MidiDevice) handleHiResCC(channel, control, value uint8) {
+	f.hiResMu.Lock()
+	updates := f.updateHiRes(channel, control, value)
+	f.hiResMu.Unlock()
+
+	for _, u := range updates {
+		if u.cc != nil {
+			if err := u.cc.callback(u.value); err != nil {
+				midiInLog.Error("failed to process 14-bit Control Change:", "err", err)
+			}
+			continue
+		}
+		f.runParam(u.param, u.value)
+	}
+}
+
+// updateHiRes applies an incoming Control Change to the running state and returns the values it completes. The
+// caller must hold f.hiResMu.
+func (f *MidiDevice) updateHiRes(channel, control, value uint8) []hiResUpdate {
+	var updates []hiResUpdate
+	for cc := range f.cc14 {
+		if cc.channel != channel {
+			continue
+		}
+		switch control {
+		case cc.msb:
+			cc.msbValue = value
+		case cc.msb + 32:
+			updates = append(updates, hiResUpdate{cc: cc, value: uint16(cc.msbValue)<<7 | uint16(value)})
+		}
+	}
+
+	if channel > 15 {
+		return updates
+	}
+	sel := &f.paramSel[channel]
+	switch control {
+	case ccNRPNMSB, ccRPNMSB:
+		sel.kind = paramNRPN
+		if control == ccRPNMSB {
+			sel.kind = paramRPN
+		}
+		sel.msb = value
+		sel.valueMSB = 0
+	case ccNRPNLSB, ccRPNLSB:
+		sel.kind = paramNRPN
+		if control == ccRPNLSB {
+			sel.kind = paramRPN
+		}
+		sel.lsb = value
+		sel.valueMSB = 0
+		// RPN 127/127 is the null parameter, which deselects so stray data entry is ignored
+		if sel.kind == paramRPN && sel.msb == 0x7F && sel.lsb == 0x7F {
+			sel.kind = paramNone
+		}
+	case ccDataEntryMSB:
+		// Senders may omit the LSB, so the MSB alone delivers a value; a following LSB refines it.
+		if sel.kind == paramNone {
+			return updates
+		}
+		sel.valueMSB = value
+		updates = append(updates, f.setParam(sel.key(channel), uint16(value)<<7))
+	case ccDataEntryLSB:
+		if sel.kind == paramNone {
+			return updates
+		}
+		updates = append(updates, f.setParam(sel.key(channel), uint16(sel.valueMSB)<<7|uint16(value)))
+	case ccDataInc, ccDataDec:
+		if sel.kind == paramNone {
+			return updates
+		}
+		key := sel.key(channel)
+		v := f.paramValues[key]
+		switch {
+		case control == ccDataInc && v < max14Bit:
+			v++
+		case control == ccDataDec && v > 0:
+			v--
+		}
+		updates = append(updates, f.setParam(key, v))
+	}
+	return updates
+}
+
+// setParam records the latest value of a parameter. The caller must hold f.hiResMu.
+func (f *MidiDevice) setParam(key paramKey, value uint16) hiResUpdate {
+	f.paramValues[key] = value
+	return hiResUpdate{param: key, value: value}
+}
+
+// runParam runs the callbacks bound to a parameter. The caller must hold f.mu for reading.
+func (f *MidiDevice) runParam(key paramKey, value uint16) {
+	midiInLog.Debug("received "+key.kind.String(), "channel", key.channel, "number", key.number, "value", value)
+	for p := range f.params {
+		if p.key() == key {
+			if err := p.callback(value); err != nil {
+				midiInLog.Error("failed to process "+key.kind.String()+":", "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package devices_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	midi "gitlab.com/gomidi/midi/v2"
+	"gitlab.com/gomidi/midi/v2/drivers"
+
+	"github.com/jdginn/arpad/devices"
+	devtest "github.com/jdginn/arpad/devices/devicestesting"
+)
+
+func TestMidiDeviceRun(t *testing.T) {
+	assert := assert.New(t)
+
+	in := devtest.NewMockMIDIPort()
+	out := devtest.NewMockMIDIPort()
+	d := devices.NewMidiDevice(in, out)
+
+	var received []uint8
+	d.CC(0, 16).Bind(func(v uint8) error {
+		received = append(received, v)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- d.Run(ctx) }()
+
+	assert.Eventually(d.IsConnected, time.Second, time.Millisecond, "device should connect")
+	assert.True(in.IsOpen())
+	assert.True(out.IsOpen())
+
+	in.SimulateReceive(midi.ControlChange(0, 16, 42))
+	assert.Equal([]uint8{42}, received)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(err)
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after context was cancelled")
+	}
+	assert.False(d.IsConnected())
+	assert.False(in.IsOpen(), "input port should be closed on shutdown")
+	assert.False(out.IsOpen(), "output port should be closed on shutdown")
+}
+
+func TestMidiDeviceRunOpenError(t *testing.T) {
+	in := devtest.NewMockMIDIPort()
+	out := devtest.NewMockMIDIPort()
+	openErr := errors.New("no such device")
+	out.SetOpenError(openErr)
+
+	err := devices.NewMidiDevice(in, out).Run(context.Background())
+	assert.ErrorIs(t, err, openErr)
+	assert.False(t, in.IsOpen(), "input port should be closed when output fails to open")
+}
+
+func TestMidiDeviceReconnect(t *testing.T) {
+	assert := assert.New(t)
+
+	in := devtest.NewMockMIDIPort()
+	out := devtest.NewMockMIDIPort()
+	lookup := devtest.NewMockPortLookup(in, out)
+	d := devices.NewMidiDevice(in, out)
+	d.SetPortLookup(lookup)
+
+	var received []uint8
+	d.CC(0, 16).Bind(func(v uint8) error {
+		received = append(received, v)
+		return nil
+	})
+	defer devtest.RunDevice(t, d.Run, d.IsConnected)()
+
+	lookup.Unplug()
+	assert.Eventually(func() bool { return !d.IsConnected() }, 3*time.Second, 10*time.Millisecond,
+		"device should notice its ports disappearing")
+	assert.False(in.IsOpen())
+	assert.False(out.IsOpen())
+
+	// The surface comes back as new ports with the same names.
+	in2 := devtest.NewMockMIDIPort()
+	out2 := devtest.NewMockMIDIPort()
+	lookup.Plug(in2, out2)
+	assert.Eventually(d.IsConnected, 3*time.Second, 10*time.Millisecond, "device should reopen replugged ports")
+
+	in2.SimulateReceive(midi.ControlChange(0, 16, 7))
+	assert.Equal([]uint8{7}, received, "bindings should survive reconnection")
+	assert.NoError(d.CC(0, 17).Set(1))
+	assert.Equal([]midi.Message{midi.ControlChange(0, 17, 1)}, out2.GetSentMessages())
+}
+
+func TestMidiDeviceMessageTypes(t *testing.T) {
+	assert := assert.New(t)
+
+	in := devtest.NewMockMIDIPort()
+	out := devtest.NewMockMIDIPort()
+	d := devices.NewMidiDevice(in, out)
+	defer devtest.RunDevice(t, d.Run, d.IsConnected)()
+
+	var program, poly, quarterFrame uint8
+	var position uint16
+	var clocks, starts, stops, continues int
+	d.ProgramChange(2).Bind(func(v uint8) error { program = v; return nil })
+	d.PolyAftertouch(0, 60).Bind(func(v uint8) error { poly = v; return nil })
+	d.Aftertouch(0).Bind(func(v uint8) error {
+		t.Error("channel pressure should not fire for poly aftertouch")
+		return nil
+	})
+	d.SongPosition().Bind(func(v uint16) error { position = v; return nil })
+	d.MTC().Bind(func(v uint8) error { quarterFrame = v; return nil })
+	d.Clock().Bind(func() error { clocks++; return nil })
+	d.Start().Bind(func() error { starts++; return nil })
+	d.Stop().Bind(func() error { stops++; return nil })
+	d.Continue().Bind(func() error { continues++; return nil })
+
+	in.SimulateReceive(midi.ProgramChange(2, 17))
+	in.SimulateReceive(midi.ProgramChange(3, 99))
+	in.SimulateReceive(midi.PolyAfterTouch(0, 60, 88))
+	in.SimulateReceive(midi.SPP(1234))
+	in.SimulateReceive(midi.MTC(0x35))
+	for range 24 {
+		in.SimulateReceive(midi.TimingClock())
+	}
+	in.SimulateReceive(midi.Start())
+	in.SimulateReceive(midi.Stop())
+	in.SimulateReceive(midi.Continue())
+
+	assert.Equal(uint8(17), program)
+	assert.Equal(uint8(88), poly)
+	assert.Equal(uint16(1234), position)
+	assert.Equal(uint8(0x35), quarterFrame)
+	assert.Equal(24, clocks)
+	assert.Equal(1, starts)
+	assert.Equal(1, stops)
+	assert.Equal(1, continues)
+
+	assert.NoError(d.ProgramChange(1).Set(5))
+	assert.NoError(d.PolyAftertouch(1, 64).Set(10))
+	assert.NoError(d.SongPosition().Set(16))
+	assert.NoError(d.MTC().Set(0x12))
+	assert.NoError(d.Clock().Set())
+	assert.NoError(d.Start().Set())
+	sent := out.GetSentMessages()
+	assert.Equal([]midi.Message{
+		midi.ProgramChange(1, 5),
+		midi.PolyAfterTouch(1, 64, 10),
+		midi.SPP(16),
+		midi.MTC(0x12),
+		midi.TimingClock(),
+		midi.Start(),
+	}, sent)
+}
+
+func TestMidiDeviceHiRes(t *testing.T) {
+	assert := assert.New(t)
+
+	in := devtest.NewMockMIDIPort()
+	out := devtest.NewMockMIDIPort()
+	d := devices.NewMidiDevice(in, out)
+	defer devtest.RunDevice(t, d.Run, d.IsConnected)()
+
+	var cc14 []uint16
+	d.CC14(0, 7).Bind(func(v uint16) error { cc14 = append(cc14, v); return nil })
+	in.SimulateReceive(midi.ControlChange(0, 7, 0x24))
+	in.SimulateReceive(midi.ControlChange(0, 39, 0x34))
+	in.SimulateReceive(midi.ControlChange(1, 39, 0x00)) // other channel
+	assert.Equal([]uint16{0x24<<7 | 0x34}, cc14, "14-bit value should be delivered once the LSB arrives")
+
+	var volume, pan, bend []uint16
+	d.NRPN(0, 0x0102).Bind(func(v uint16) error { volume = append(volume, v); return nil })
+	d.NRPN(1, 0x0003).Bind(func(v uint16) error { pan = append(pan, v); return nil })
+	d.RPN(0, 0x0000).Bind(func(v uint16) error { bend = append(bend, v); return nil })
+
+	// Parameter selection is tracked per channel, so data entry on channel 1 does not disturb channel 0.
+	in.SimulateReceive(midi.ControlChange(0, 99, 0x02))
+	in.SimulateReceive(midi.ControlChange(0, 98, 0x02))
+	in.SimulateReceive(midi.ControlChange(1, 99, 0x00))
+	in.SimulateReceive(midi.ControlChange(1, 98, 0x03))
+	in.SimulateReceive(midi.ControlChange(0, 6, 0x10))
+	in.SimulateReceive(midi.ControlChange(1, 6, 0x40))
+	in.SimulateReceive(midi.ControlChange(1, 38, 0x00))
+	in.SimulateReceive(midi.ControlChange(0, 38, 0x05))
+	in.SimulateReceive(midi.ControlChange(0, 96, 0x00))
+	assert.Equal([]uint16{0x10 << 7, 0x10<<7 | 0x05, 0x10<<7 | 0x06}, volume, "data entry MSB delivers, LSB refines")
+	assert.Equal([]uint16{0x40 << 7, 0x40 << 7}, pan)
+
+	// Selecting a new parameter clears the previous data entry MSB.
+	in.SimulateReceive(midi.ControlChange(1, 99, 0x00))
+	in.SimulateReceive(midi.ControlChange(1, 98, 0x03))
+	in.SimulateReceive(midi.ControlChange(1, 38, 0x02))
+	assert.Equal(uint16(0x02), pan[len(pan)-1])
+
+	// Switching to an RPN on the same channel redirects data entry, and the null RPN stops it.
+	in.SimulateReceive(midi.ControlChange(0, 101, 0x00))
+	in.SimulateReceive(midi.ControlChange(0, 100, 0x00))
+	in.SimulateReceive(midi.ControlChange(0, 6, 0x02))
+	in.SimulateReceive(midi.ControlChange(0, 38, 0x00))
+	in.SimulateReceive(midi.ControlChange(0, 101, 0x7F))
+	in.SimulateReceive(midi.ControlChange(0, 100, 0x7F))
+	in.SimulateReceive(midi.ControlChange(0, 6, 0x05))
+	in.SimulateReceive(midi.ControlChange(0, 38, 0x00))
+	assert.Equal([]uint16{0x02 << 7, 0x02 << 7}, bend)
+	assert.Len(volume, 3)
+
+	assert.NoError(d.CC14(2, 1).Set(0x3FFF))
+	assert.NoError(d.NRPN(3, 0x0102).Set(0x0081))
+	assert.Error(d.NRPN(3, 0x0102).Set(0x4000))
+	assert.Error(d.CC14(2, 96).Set(0), "controllers above 31 have no LSB partner")
+	assert.Equal([]midi.Message{
+		midi.ControlChange(2, 1, 0x7F),
+		midi.ControlChange(2, 33, 0x7F),
+		midi.ControlChange(3, 99, 0x02),
+		midi.ControlChange(3, 98, 0x02),
+		midi.ControlChange(3, 6, 0x01),
+		midi.ControlChange(3, 38, 0x01),
+	}, out.GetSentMessages())
+}
+
+func TestNewVirtualMidiDeviceUnsupported(t *testing.T) {
+	// No driver is registered in tests, so virtual ports cannot be created.
+	_, err := devices.NewVirtualMidiDevice("arpad")
+	assert.ErrorIs(t, err, devices.ErrVirtualPortsUnsupported)
+}
+
+func TestNewVirtualMidiDevice(t *testing.T) {
+	assert := assert.New(t)
+
+	drv := devtest.NewMockVirtualDriver()
+	drivers.Register(drv)
+	t.Cleanup(func() { delete(drivers.REGISTRY, drv.String()) })
+
+	d, err := devices.NewVirtualMidiDevice("arpad")
+	assert.NoError(err)
+	assert.True(d.IsVirtual())
+
+	var received []uint8
+	d.CC(0, 16).Bind(func(v uint8) error {
+		received = append(received, v)
+		return nil
+	})
+
+	stop := devtest.RunDevice(t, d.Run, d.IsConnected)
+	ins, outs := drv.VirtualPorts()
+	assert.Len(ins, 1)
+	ins[0].SimulateReceive(midi.ControlChange(0, 16, 3))
+	assert.NoError(d.CC(0, 17).Set(4))
+	assert.Equal([]uint8{3}, received)
+	assert.Equal([]midi.Message{midi.ControlChange(0, 17, 4)}, outs[0].GetSentMessages())
+	stop()
+	assert.False(ins[0].IsOpen(), "virtual ports should be closed on shutdown")
+	assert.False(outs[0].IsOpen(), "virtual ports should be closed on shutdown")
+
+	// Running again recreates the closed virtual ports.
+	defer devtest.RunDevice(t, d.Run, d.IsConnected)()
+	ins, outs = drv.VirtualPorts()
+	assert.Len(ins, 2)
+	assert.Len(outs, 2)
+	ins[1].SimulateReceive(midi.ControlChange(0, 16, 5))
+	assert.Equal([]uint8{3, 5}, received)
+}
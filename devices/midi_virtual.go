@@ -0,0 +1,59 @@
+package devices
+
+import (
+	"errors"
+	"fmt"
+
+	"gitlab.com/gomidi/midi/v2/drivers"
+)
+
+// virtualPortDriver is implemented by MIDI drivers that can create virtual ports, such as rtmididrv on Linux
+// (ALSA) and macOS (CoreMIDI).
+type virtualPortDriver interface {
+	OpenVirtualIn(name string) (drivers.In, error)
+	OpenVirtualOut(name string) (drivers.Out, error)
+}
+
+// ErrVirtualPortsUnsupported is returned when the registered MIDI driver cannot create virtual ports.
+var ErrVirtualPortsUnsupported = errors.New("registered MIDI driver does not support virtual ports")
+
+// NewVirtualMidiDevice creates a virtual input and output port with the given name and returns a MidiDevice
+// wrapping them.
+//
+// Other applications (e.g. a DAW) see the ports as a MIDI device called name and can connect to arpad directly,
+// without a loopback bus. Messages the DAW sends arrive on this device's bindings and messages Set on this device
+// are delivered to the DAW.
+//
+// A driver that supports virtual ports must be registered first, e.g. by importing
+// gitlab.com/gomidi/midi/v2/drivers/rtmididrv.
+func NewVirtualMidiDevice(name string) (*MidiDevice, error) {
+	in, out, err := openVirtualPorts(name)
+	if err != nil {
+		return nil, err
+	}
+	d := NewMidiDevice(in, out)
+	d.virtualName = name
+	return d, nil
+}
+
+func openVirtualPorts(name string) (drivers.In, drivers.Out, error) {
+	drv, ok := drivers.Get().(virtualPortDriver)
+	if !ok {
+		return nil, nil, ErrVirtualPortsUnsupported
+	}
+	in, err := drv.OpenVirtualIn(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create virtual MIDI input port %s: %w", name, err)
+	}
+	out, err := drv.OpenVirtualOut(name)
+	if err != nil {
+		in.Close()
+		return nil, nil, fmt.Errorf("failed to create virtual MIDI output port %s: %w", name, err)
+	}
+	return in, out, nil
+}
+
+// IsVirtual reports whether this device owns virtual ports rather than wrapping ports of an external device.
+func (d *MidiDevice) IsVirtual() bool {
+	return d.virtualName != ""
+}
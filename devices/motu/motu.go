@@ -15,6 +15,7 @@ type MOTU struct {
 	AVB    *AVBBindings
 	// Router *RouterBindings
 	Mixer *MixerBindings
+	Ext   *ExtBindings
 }
 
 // NewMOTU creates a new MOTU connection with all bindings initialized
@@ -24,6 +25,7 @@ func NewMOTU(d *HTTPDatastore) *MOTU {
 	m.AVB = newAVBBindings(m)
 	// m.Router = newRouterBindings(m)
 	m.Mixer = newMixerBindings(m)
+	m.Ext = newExtBindings(m)
 	return m
 }
 
@@ -118,6 +120,39 @@ func (e *VendorNameEndpoint) Bind(deviceUID string, callback func(string) error)
 	e.m.d.BindString(path, callback)
 }
 
+// Ext section
+type ExtBindings struct {
+	m         *MOTU
+	InputTrim *InputTrimEndpoint
+}
+
+func newExtBindings(m *MOTU) *ExtBindings {
+	return &ExtBindings{
+		m:         m,
+		InputTrim: &InputTrimEndpoint{m: m},
+	}
+}
+
+type PathInputChannel struct {
+	BankIndex    int64
+	ChannelIndex int64
+}
+
+type InputTrimEndpoint struct {
+	m *MOTU
+}
+
+func (e *InputTrimEndpoint) Bind(p PathInputChannel, callback func(int64) error) {
+	path := fmt.Sprintf("ext/ibank/%d/ch/%d/trim", p.BankIndex, p.ChannelIndex)
+	e.m.d.BindInt(path, callback)
+}
+
+// Set trims the input channel by val dB. The allowed range depends on the channel and is reported by trimRange.
+func (e *InputTrimEndpoint) Set(p PathInputChannel, val int64) error {
+	path := fmt.Sprintf("ext/ibank/%d/ch/%d/trim", p.BankIndex, p.ChannelIndex)
+	return e.m.d.SetInt(path, val)
+}
+
 // Mixer section
 type MixerBindings struct {
 	m      *MOTU
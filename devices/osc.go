@@ -3,7 +3,8 @@ package devices
 import (
 	"fmt"
 	"log/slog"
-	"strconv"
+	"sync/atomic"
+	"time"
 
 	"github.com/hypebeast/go-osc/osc"
 
@@ -22,8 +23,13 @@ type Dispatcher interface {
 	AddMsgHandler(string, func(*osc.Message)) func()
 }
 
+// OscSender sends packets to the remote end of an OscDevice. *osc.Client is the UDP implementation.
+type OscSender interface {
+	Send(osc.Packet) error
+}
+
 type OscDevice struct {
-	Client     *osc.Client
+	Client     OscSender
 	Server     *osc.Server
 	Dispatcher Dispatcher
 
@@ -31,6 +37,12 @@ type OscDevice struct {
 	clientPort int
 	serverIP   string
 	serverPort int
+
+	// tcp is set for devices created by NewTCPOscDevice, which send and receive over the same stream.
+	tcp *oscTCPConn
+
+	// argErrors counts incoming messages whose arguments could not be converted for their binding.
+	argErrors atomic.Uint64
 }
 
 func NewOscDevice(clientIp string, clientPort int, serverIp string, serverPort int, dispatcher Dispatcher) *OscDevice {
@@ -53,8 +65,12 @@ func NewOscDevice(clientIp string, clientPort int, serverIp string, serverPort i
 // 	return NewOscDevice(client, server, dispatcher), nil
 // }
 
+// Run receives packets and dispatches them to bound callbacks. For UDP devices it serves on the configured
+// address; for TCP devices it maintains the connection, reconnecting as needed, until Close is called.
 func (o *OscDevice) Run() error {
-	// Now Run() just starts the server
+	if o.tcp != nil {
+		return o.tcp.run(o.Dispatcher)
+	}
 	o.Server = &osc.Server{
 		Addr:       fmt.Sprintf("%s:%d", o.serverIP, o.serverPort),
 		Dispatcher: o.Dispatcher,
@@ -62,6 +78,23 @@ func (o *OscDevice) Run() error {
 	return o.Server.ListenAndServe()
 }
 
+// Close stops a running TCP device and closes its connection. Run then returns nil.
+func (o *OscDevice) Close() error {
+	if o.tcp != nil {
+		return o.tcp.close()
+	}
+	return nil
+}
+
+// Connected reports whether a TCP device currently has a connection. UDP devices are connectionless and always
+// report true.
+func (o *OscDevice) Connected() bool {
+	if o.tcp != nil {
+		return o.tcp.Connected()
+	}
+	return true
+}
+
 func (o *OscDevice) SetInt(key string, val int64) error {
 	oscOutLog.Debug("Sending OSC message", slog.String("address", key), slog.Any("arguments", val))
 	return o.Client.Send(osc.NewMessage(key, int32(val)))
@@ -82,231 +115,137 @@ func (o *OscDevice) SetBool(key string, val bool) error {
 	return o.Client.Send(osc.NewMessage(key, val))
 }
 
+// SetArgs sends a message carrying all of args. Arguments are encoded according to their Go type: int32, int64,
+// float32, float64, string, bool, []byte (blob), osc.Timetag and nil are supported.
+func (o *OscDevice) SetArgs(key string, args ...any) error {
+	oscOutLog.Debug("Sending OSC message", slog.String("address", key), slog.Any("arguments", args))
+	return o.Client.Send(osc.NewMessage(key, args...))
+}
+
+// ArgumentErrors returns the number of incoming messages dropped because their arguments could not be converted
+// to the type expected by their binding.
+func (o *OscDevice) ArgumentErrors() uint64 {
+	return o.argErrors.Load()
+}
+
 // BindInt binds a callback to run whenever a message is received for the given OSC address.
 //
-// The given address should return a value that can be interpreted as an int.
-//
-// WARNING: Conversions are best-effort and could panic if the value cannot be interpreted as an int.
+// The first argument is converted to an int; a message with no arguments or a nil argument delivers 0. Messages
+// whose argument cannot be interpreted as an int are logged, counted in ArgumentErrors and dropped.
 func (o *OscDevice) BindInt(addr string, effect func(int64) error) func() {
-	return o.Dispatcher.AddMsgHandler(addr, func(msg *osc.Message) {
-		var val any
-		if len(msg.Arguments) == 0 {
-			val = 0
-		} else {
-			val = msg.Arguments[0]
-			if val == nil {
-				val = 0
-			}
-		}
-		switch val := val.(type) {
-		case int:
-			if err := effect(int64(val)); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		case int32:
-			if err := effect(int64(val)); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		case int64:
-			if err := effect(int64(val)); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		case float64:
-			if err := effect(int64(val)); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		case float32:
-			if err := effect(int64(val)); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		case string:
-			asInt, err := strconv.Atoi(val)
-			if err != nil {
-				oscInLog.Error("Error converting string to int when handling OSC route", slog.String("route", msg.Address), slog.Any("value", val), slog.Any("err", err))
-				return
-			}
-			if err := effect(int64(asInt)); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		default:
-			panic(fmt.Sprintf("Unsupported message type %T", val))
-		}
-	})
+	return bindFirst(o, addr, oscToInt, effect)
 }
 
 // BindFloat binds a callback to run whenever a message is received for the given OSC address.
 //
-// The given address MUST return a float or be convertable to float.
-// WARNING: Conversions are best-effort and could panic.
+// The first argument is converted to a float; a message with no arguments or a nil argument delivers 0. Messages
+// whose argument cannot be interpreted as a float are logged, counted in ArgumentErrors and dropped.
 func (o *OscDevice) BindFloat(key string, effect func(float64) error) func() {
-	return o.Dispatcher.AddMsgHandler(key, func(msg *osc.Message) {
-		// oscInLog.Debug("Received OSC message", slog.String("address", msg.Address), slog.Any("arguments", msg.Arguments))
-		var val any
-		if len(msg.Arguments) == 0 {
-			val = 0.0
-		} else {
-			val = msg.Arguments[0]
-			if val == nil {
-				val = 0.0
-			}
-		}
-		switch val := val.(type) {
-		case float64:
-			if err := effect(val); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		case float32:
-			if err := effect(float64(val)); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		case int:
-			if err := effect(float64(val)); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		case int32:
-			if err := effect(float64(val)); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		case int64:
-			if err := effect(float64(val)); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		case string:
-			asNum, err := strconv.Atoi(val)
-			if err != nil {
-				oscInLog.Error("Error converting string to int when handling OSC route", slog.String("route", msg.Address), slog.Any("value", val), slog.Any("err", err))
-				return
-			}
-			if err := effect(float64(asNum)); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		default:
-			panic(fmt.Sprintf("Unsupported message type %T", val))
-		}
-	})
+	return bindFirst(o, key, oscToFloat, effect)
 }
 
 // BindString binds a callback to run whenever a message is received for the given OSC address.
 //
-// The given address should return a value that can be interpreted as a string.
-//
-// WARNING: Conversions are best-effort and could panic if the value cannot be interpreted as a string.
+// The first argument is converted to a string; a message with no arguments or a nil argument delivers "".
 func (o *OscDevice) BindString(key string, effect func(string) error) func() {
-	return o.Dispatcher.AddMsgHandler(key, func(msg *osc.Message) {
-		oscInLog.Debug("Received OSC message", slog.String("address", msg.Address), slog.Any("arguments", msg.Arguments))
-		var val any
-		if len(msg.Arguments) == 0 {
-			val = ""
-		} else {
-			val = msg.Arguments[0]
-			if val == nil {
-				val = ""
-			}
-		}
-		switch val := val.(type) {
-		case float64:
-			if err := effect(fmt.Sprintf("%f", val)); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		case float32:
-			if err := effect(fmt.Sprintf("%f", val)); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		case int:
-			if err := effect(fmt.Sprintf("%d", val)); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		case int32:
-			if err := effect(fmt.Sprintf("%d", val)); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		case int64:
-			if err := effect(fmt.Sprintf("%d", val)); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		case string:
-			if err := effect(val); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		default:
-			panic(fmt.Sprintf("Unsupported message type %T", val))
-		}
-	})
+	return bindFirst(o, key, oscToString, effect)
 }
 
 // BindBool binds a callback to run whenever a message is received for the given OSC address.
 //
-// The given address should return a value that can be interpreted as a boolean.
-//
-// WARNING: Conversions are best-effort and could panic if the value cannot be interpreted as a boolean.
+// The first argument is converted to a bool; a message with no arguments or a nil argument delivers false.
+// Messages whose argument cannot be interpreted as a bool are logged, counted in ArgumentErrors and dropped.
 func (o *OscDevice) BindBool(key string, effect func(bool) error) func() {
-	return o.Dispatcher.AddMsgHandler(key, func(msg *osc.Message) {
-		// oscInLog.Debug("Received OSC message", slog.String("address", msg.Address), slog.Any("arguments", msg.Arguments))
-		var val any
-		if len(msg.Arguments) == 0 {
-			val = false
-		} else {
-			val = msg.Arguments[0]
-			if val == nil {
-				val = false
-			}
+	return bindFirst(o, key, oscToBool, effect)
+}
+
+// BindBlob binds a callback to run with the first argument of each message received for the given OSC address,
+// which must be a blob.
+func (o *OscDevice) BindBlob(addr string, effect func([]byte) error) func() {
+	return bindFirst(o, addr, oscToBlob, effect)
+}
+
+// BindTimetag binds a callback to run with the first argument of each message received for the given OSC
+// address, which must be a timetag.
+func (o *OscDevice) BindTimetag(addr string, effect func(time.Time) error) func() {
+	return bindFirst(o, addr, oscToTime, effect)
+}
+
+// BindImpulse binds a callback to run whenever a message is received for the given OSC address, regardless of
+// its arguments. Use it for trigger-style addresses that carry nil, an impulse or nothing at all.
+func (o *OscDevice) BindImpulse(addr string, effect func() error) func() {
+	return o.Dispatcher.AddMsgHandler(addr, func(msg *osc.Message) {
+		o.report(msg, effect())
+	})
+}
+
+// BindArgs binds a callback to run with all arguments of each message received for the given OSC address.
+//
+// Arguments are delivered as decoded by the OSC library: int32, int64, float32, float64, string, bool, []byte
+// (blob), osc.Timetag or nil.
+func (o *OscDevice) BindArgs(addr string, effect func(args []any) error) func() {
+	return o.Dispatcher.AddMsgHandler(addr, func(msg *osc.Message) {
+		o.report(msg, effect(msg.Arguments))
+	})
+}
+
+// BindInts binds a callback to run with every argument of each message received for the given OSC address,
+// converted to ints.
+func (o *OscDevice) BindInts(addr string, effect func([]int64) error) func() {
+	return bindAll(o, addr, oscToInt, effect)
+}
+
+// BindFloats binds a callback to run with every argument of each message received for the given OSC address,
+// converted to floats. This suits addresses such as /meter that carry several values per message.
+func (o *OscDevice) BindFloats(addr string, effect func([]float64) error) func() {
+	return bindAll(o, addr, oscToFloat, effect)
+}
+
+// BindStrings binds a callback to run with every argument of each message received for the given OSC address,
+// converted to strings.
+func (o *OscDevice) BindStrings(addr string, effect func([]string) error) func() {
+	return bindAll(o, addr, oscToString, effect)
+}
+
+// bindFirst binds effect to the first argument of each message for addr, converted with convert.
+func bindFirst[T any](o *OscDevice, addr string, convert func(any) (T, error), effect func(T) error) func() {
+	return o.Dispatcher.AddMsgHandler(addr, func(msg *osc.Message) {
+		var arg any
+		if len(msg.Arguments) > 0 {
+			arg = msg.Arguments[0]
 		}
-		switch val := val.(type) {
-		case bool:
-			if err := effect(val); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		case float64:
-			if err := effect(val > 0); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		case float32:
-			if err := effect(val > 0); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		case int:
-			if err := effect(val > 0); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		case int32:
-			if err := effect(val > 0); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		case int64:
-			if err := effect(val > 0); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
-				return
-			}
-		case string:
-			if err := effect(val == "true"); err != nil {
-				oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
+		val, err := convert(arg)
+		if err != nil {
+			o.argumentError(msg, err)
+			return
+		}
+		o.report(msg, effect(val))
+	})
+}
+
+// bindAll binds effect to every argument of each message for addr, converted with convert.
+func bindAll[T any](o *OscDevice, addr string, convert func(any) (T, error), effect func([]T) error) func() {
+	return o.Dispatcher.AddMsgHandler(addr, func(msg *osc.Message) {
+		vals := make([]T, len(msg.Arguments))
+		for i, arg := range msg.Arguments {
+			val, err := convert(arg)
+			if err != nil {
+				o.argumentError(msg, fmt.Errorf("argument %d: %w", i, err))
 				return
 			}
-		default:
-			panic(fmt.Sprintf("Unsupported message type %T", val))
+			vals[i] = val
 		}
+		o.report(msg, effect(vals))
 	})
 }
+
+func (o *OscDevice) argumentError(msg *osc.Message, err error) {
+	o.argErrors.Add(1)
+	oscInLog.Error("Unable to convert arguments for osc route", slog.String("route", msg.Address), slog.Any("arguments", msg.Arguments), slog.Any("err", err))
+}
+
+func (o *OscDevice) report(msg *osc.Message, err error) {
+	if err != nil {
+		oscInLog.Error("Error in function bound to osc route", slog.String("route", msg.Address), slog.Any("err", err))
+	}
+}
@@ -0,0 +1,146 @@
+package devices
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// Conversions from decoded OSC arguments to the types bindings ask for. A nil argument, which is also what a
+// binding sees for a message with no arguments, converts to the zero value.
+
+func oscToInt(arg any) (int64, error) {
+	switch v := arg.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case float32:
+		return int64(v), nil
+	case float64:
+		return int64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		asInt, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("converting string %q to int: %w", v, err)
+		}
+		return asInt, nil
+	default:
+		return 0, fmt.Errorf("cannot convert %T to int", arg)
+	}
+}
+
+func oscToFloat(arg any) (float64, error) {
+	switch v := arg.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case float32:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		asFloat, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("converting string %q to float: %w", v, err)
+		}
+		return asFloat, nil
+	default:
+		return 0, fmt.Errorf("cannot convert %T to float", arg)
+	}
+}
+
+func oscToString(arg any) (string, error) {
+	switch v := arg.(type) {
+	case nil:
+		return "", nil
+	case int, int32, int64:
+		return fmt.Sprintf("%d", v), nil
+	case float32, float64:
+		return fmt.Sprintf("%f", v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case osc.Timetag, *osc.Timetag:
+		t, _ := oscToTime(v)
+		return t.Format(time.RFC3339Nano), nil
+	default:
+		return "", fmt.Errorf("cannot convert %T to string", arg)
+	}
+}
+
+func oscToBool(arg any) (bool, error) {
+	switch v := arg.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	case int:
+		return v > 0, nil
+	case int32:
+		return v > 0, nil
+	case int64:
+		return v > 0, nil
+	case float32:
+		return v > 0, nil
+	case float64:
+		return v > 0, nil
+	case string:
+		return v == "true", nil
+	default:
+		return false, fmt.Errorf("cannot convert %T to bool", arg)
+	}
+}
+
+func oscToBlob(arg any) ([]byte, error) {
+	switch v := arg.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("cannot convert %T to blob", arg)
+	}
+}
+
+func oscToTime(arg any) (time.Time, error) {
+	switch v := arg.(type) {
+	case osc.Timetag:
+		return v.Time(), nil
+	case *osc.Timetag:
+		if v == nil {
+			return time.Time{}, nil
+		}
+		return v.Time(), nil
+	case nil:
+		return time.Time{}, nil
+	default:
+		return time.Time{}, fmt.Errorf("cannot convert %T to timetag", arg)
+	}
+}
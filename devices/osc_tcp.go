@@ -0,0 +1,282 @@
+package devices
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// OscFraming selects how OSC packets are delimited on a stream transport such as TCP.
+type OscFraming int
+
+const (
+	// SLIPFraming delimits packets with double-ended SLIP (RFC 1055), as specified by OSC 1.1.
+	SLIPFraming OscFraming = iota
+	// LengthPrefixFraming precedes each packet with its size as a big-endian int32, as specified by OSC 1.0.
+	LengthPrefixFraming
+)
+
+func (f OscFraming) String() string {
+	switch f {
+	case SLIPFraming:
+		return "SLIP"
+	case LengthPrefixFraming:
+		return "length-prefix"
+	default:
+		return fmt.Sprintf("OscFraming(%d)", int(f))
+	}
+}
+
+// SLIP special bytes.
+const (
+	slipEnd    byte = 0xC0
+	slipEsc    byte = 0xDB
+	slipEscEnd byte = 0xDC
+	slipEscEsc byte = 0xDD
+)
+
+const (
+	oscDialTimeout     = 2 * time.Second
+	oscReconnectMin    = 100 * time.Millisecond
+	oscReconnectMax    = 5 * time.Second
+	oscMaxPacketLength = 1 << 24
+)
+
+// ErrOscNotConnected is returned when sending on a stream transport that is currently disconnected.
+var ErrOscNotConnected = errors.New("OSC connection is not established")
+
+// NewTCPOscDevice returns an OscDevice that exchanges packets with the server at ip:port over a single TCP
+// connection using the given framing. Run dials the server and redials with backoff whenever the connection drops.
+//
+// The Bind* and Set* methods behave exactly as for a UDP device. Sends made while disconnected fail with
+// ErrOscNotConnected.
+func NewTCPOscDevice(ip string, port int, framing OscFraming, dispatcher Dispatcher) *OscDevice {
+	conn := &oscTCPConn{
+		addr:    net.JoinHostPort(ip, fmt.Sprint(port)),
+		framing: framing,
+		done:    make(chan struct{}),
+	}
+	return &OscDevice{
+		Client:     conn,
+		Dispatcher: dispatcher,
+		clientIP:   ip,
+		clientPort: port,
+		tcp:        conn,
+	}
+}
+
+// oscTCPConn sends and receives framed OSC packets over a TCP connection that is re-established when it drops.
+type oscTCPConn struct {
+	addr    string
+	framing OscFraming
+
+	// mu guards conn and closed, and serializes writes so frames never interleave.
+	mu     sync.Mutex
+	conn   net.Conn
+	closed bool
+	done   chan struct{}
+}
+
+// Send frames packet and writes it to the current connection.
+func (c *oscTCPConn) Send(packet osc.Packet) error {
+	data, err := packet.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	frame := encodeOscFrame(c.framing, data)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return ErrOscNotConnected
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		// The read loop notices the closed connection and reconnects.
+		c.conn.Close()
+		c.conn = nil
+		return err
+	}
+	return nil
+}
+
+// Connected reports whether a connection is currently established.
+func (c *oscTCPConn) Connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn != nil
+}
+
+// run keeps a connection open until close is called, dispatching every packet received on it.
+func (c *oscTCPConn) run(dispatcher osc.Dispatcher) error {
+	backoff := oscReconnectMin
+	for {
+		select {
+		case <-c.done:
+			return nil
+		default:
+		}
+
+		conn, err := net.DialTimeout("tcp", c.addr, oscDialTimeout)
+		if err != nil {
+			oscInLog.Debug("Failed to connect to OSC server", slog.String("addr", c.addr), slog.Any("err", err))
+			select {
+			case <-c.done:
+				return nil
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, oscReconnectMax)
+			continue
+		}
+		if !c.attach(conn) {
+			conn.Close()
+			return nil
+		}
+		backoff = oscReconnectMin
+		oscInLog.Info("Connected to OSC server", slog.String("addr", c.addr), slog.String("framing", c.framing.String()))
+
+		err = c.readLoop(conn, dispatcher)
+		c.detach(conn)
+		select {
+		case <-c.done:
+			return nil
+		default:
+		}
+		oscInLog.Warn("Lost connection to OSC server", slog.String("addr", c.addr), slog.Any("err", err))
+	}
+}
+
+// attach makes conn the current connection. It returns false if the transport has been closed.
+func (c *oscTCPConn) attach(conn net.Conn) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	c.conn = conn
+	return true
+}
+
+// detach closes conn and forgets it if it is still the current connection.
+func (c *oscTCPConn) detach(conn net.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	conn.Close()
+	if c.conn == conn {
+		c.conn = nil
+	}
+}
+
+func (c *oscTCPConn) readLoop(conn net.Conn, dispatcher osc.Dispatcher) error {
+	r := bufio.NewReader(conn)
+	for {
+		data, err := readOscFrame(c.framing, r)
+		if err != nil {
+			return err
+		}
+		packet, err := osc.ParsePacket(string(data))
+		if err != nil {
+			oscInLog.Error("Failed to parse OSC packet", slog.Any("err", err))
+			continue
+		}
+		dispatcher.Dispatch(packet)
+	}
+}
+
+// close stops run and closes the current connection.
+func (c *oscTCPConn) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	close(c.done)
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+	return nil
+}
+
+// encodeOscFrame wraps a marshalled packet for transmission on a stream.
+func encodeOscFrame(framing OscFraming, data []byte) []byte {
+	if framing == LengthPrefixFraming {
+		frame := make([]byte, 4, 4+len(data))
+		binary.BigEndian.PutUint32(frame, uint32(len(data)))
+		return append(frame, data...)
+	}
+
+	frame := make([]byte, 0, len(data)+2)
+	frame = append(frame, slipEnd)
+	for _, b := range data {
+		switch b {
+		case slipEnd:
+			frame = append(frame, slipEsc, slipEscEnd)
+		case slipEsc:
+			frame = append(frame, slipEsc, slipEscEsc)
+		default:
+			frame = append(frame, b)
+		}
+	}
+	return append(frame, slipEnd)
+}
+
+// readOscFrame reads the next packet from a stream. Empty SLIP frames, such as those between two END bytes, are
+// skipped.
+func readOscFrame(framing OscFraming, r *bufio.Reader) ([]byte, error) {
+	if framing == LengthPrefixFraming {
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return nil, err
+		}
+		n := binary.BigEndian.Uint32(size[:])
+		if n > oscMaxPacketLength {
+			return nil, fmt.Errorf("OSC packet length %d exceeds maximum of %d", n, oscMaxPacketLength)
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+
+	var data []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch b {
+		case slipEnd:
+			if len(data) > 0 {
+				return data, nil
+			}
+		case slipEsc:
+			next, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			switch next {
+			case slipEscEnd:
+				data = append(data, slipEnd)
+			case slipEscEsc:
+				data = append(data, slipEsc)
+			default:
+				return nil, fmt.Errorf("invalid SLIP escape 0x%02X", next)
+			}
+		default:
+			if len(data) >= oscMaxPacketLength {
+				return nil, fmt.Errorf("OSC packet exceeds maximum length of %d", oscMaxPacketLength)
+			}
+			data = append(data, b)
+		}
+	}
+}
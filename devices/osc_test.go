@@ -1,11 +1,17 @@
 package devices_test
 
 import (
+	"bufio"
+	"encoding/binary"
 	"fmt"
+	"io"
 	"math"
+	"net"
 	"testing"
 	"time"
 
+	"github.com/hypebeast/go-osc/osc"
+	"github.com/jdginn/arpad/devices"
 	"github.com/jdginn/arpad/devices/devicestesting"
 	devtest "github.com/jdginn/arpad/devices/devicestesting"
 	"github.com/stretchr/testify/assert"
@@ -630,3 +636,262 @@ func TestOscDevice_Performance(t *testing.T) {
 			messageCount, duration, duration/time.Duration(messageCount))
 	})
 }
+
+// exactDispatcher delivers messages to handlers registered for exactly their address.
+type exactDispatcher struct {
+	handlers map[string][]func(*osc.Message)
+}
+
+func (d *exactDispatcher) AddMsgHandler(addr string, handler func(*osc.Message)) func() {
+	d.handlers[addr] = append(d.handlers[addr], handler)
+	return func() {}
+}
+
+func (d *exactDispatcher) Dispatch(packet osc.Packet) {
+	if msg, ok := packet.(*osc.Message); ok {
+		for _, handler := range d.handlers[msg.Address] {
+			handler(msg)
+		}
+	}
+}
+
+func (d *exactDispatcher) send(addr string, args ...any) {
+	d.Dispatch(osc.NewMessage(addr, args...))
+}
+
+func TestOscDevice_Arguments(t *testing.T) {
+	assert := assert.New(t)
+
+	disp := &exactDispatcher{handlers: make(map[string][]func(*osc.Message))}
+	d := devices.NewOscDevice("127.0.0.1", 0, "127.0.0.1", 0, disp)
+
+	var args []any
+	var meters []float64
+	var blob []byte
+	var stamp time.Time
+	var ints, impulses int
+	d.BindArgs("/args", func(v []any) error { args = v; return nil })
+	d.BindFloats("/meter", func(v []float64) error { meters = v; return nil })
+	d.BindBlob("/blob", func(v []byte) error { blob = v; return nil })
+	d.BindTimetag("/time", func(v time.Time) error { stamp = v; return nil })
+	d.BindInt("/int", func(int64) error { ints++; return nil })
+	d.BindImpulse("/trigger", func() error { impulses++; return nil })
+
+	disp.send("/args", int32(1), "two", true, nil)
+	assert.Equal([]any{int32(1), "two", true, nil}, args)
+
+	disp.send("/meter", float32(0.5), float64(0.25), int32(1))
+	assert.Equal([]float64{0.5, 0.25, 1}, meters)
+
+	disp.send("/blob", []byte{0x01, 0x02})
+	assert.Equal([]byte{0x01, 0x02}, blob)
+
+	now := time.Unix(1700000000, 0)
+	disp.send("/time", *osc.NewTimetag(now))
+	assert.WithinDuration(now, stamp, time.Millisecond)
+
+	disp.send("/trigger")
+	disp.send("/trigger", nil)
+	assert.Equal(2, impulses)
+
+	assert.Equal(uint64(0), d.ArgumentErrors())
+	assert.NotPanics(func() {
+		disp.send("/int", []byte{0x01})
+		disp.send("/int", "not a number")
+		disp.send("/meter", float32(1), []byte{0x01})
+		disp.send("/blob", int32(3))
+	})
+	assert.Equal(0, ints, "unconvertible arguments must not reach the callback")
+	assert.Equal([]float64{0.5, 0.25, 1}, meters)
+	assert.Equal(uint64(4), d.ArgumentErrors())
+}
+
+func TestOscDevice_Batch(t *testing.T) {
+	assert := assert.New(t)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(err)
+	defer conn.Close()
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	d := devices.NewOscDevice("127.0.0.1", port, "127.0.0.1", 0, &exactDispatcher{})
+
+	receive := func() osc.Packet {
+		buf := make([]byte, 65535)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		assert.NoError(err)
+		packet, err := osc.ParsePacket(string(buf[:n]))
+		assert.NoError(err)
+		return packet
+	}
+
+	assert.NoError(d.Batch(func(tx *devices.OscTx) error {
+		tx.SetInt("/track/1/mute", 1)
+		tx.SetFloat("/track/1/volume", 0.5)
+		tx.SetString("/track/1/name", "Drums")
+		return nil
+	}))
+	bundle, ok := receive().(*osc.Bundle)
+	assert.True(ok, "batched messages should arrive as one bundle")
+	assert.Len(bundle.Messages, 3)
+	assert.Equal("/track/1/volume", bundle.Messages[1].Address)
+	assert.Equal(uint64(1), bundle.Timetag.TimeTag(), "bundles without a time should apply immediately")
+
+	at := time.Now().Add(time.Minute)
+	tx := d.BeginAt(at)
+	assert.NoError(tx.Commit(), "committing an empty transaction is a no-op")
+	tx.SetBool("/play", true)
+	assert.Equal(1, tx.Len())
+	assert.NoError(tx.Commit())
+	assert.Equal(0, tx.Len())
+	bundle, ok = receive().(*osc.Bundle)
+	assert.True(ok)
+	assert.WithinDuration(at, bundle.Timetag.Time(), time.Millisecond)
+
+	assert.Error(d.Batch(func(tx *devices.OscTx) error {
+		tx.SetInt("/never", 1)
+		return fmt.Errorf("abort")
+	}))
+}
+
+func TestOscDevice_TCP(t *testing.T) {
+	// A blob containing the SLIP END and ESC bytes exercises escaping.
+	payload := []byte{0x01, 0xC0, 0xDB, 0x02}
+
+	for _, tc := range []struct {
+		framing devices.OscFraming
+		encode  func([]byte) []byte
+		decode  func(*bufio.Reader) ([]byte, error)
+	}{
+		{devices.SLIPFraming, slipEncode, slipDecode},
+		{devices.LengthPrefixFraming, lengthEncode, lengthDecode},
+	} {
+		t.Run(tc.framing.String(), func(t *testing.T) {
+			assert := assert.New(t)
+
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			assert.NoError(err)
+			defer ln.Close()
+			conns := make(chan net.Conn)
+			go func() {
+				for {
+					conn, err := ln.Accept()
+					if err != nil {
+						return
+					}
+					conns <- conn
+				}
+			}()
+
+			port := ln.Addr().(*net.TCPAddr).Port
+			d := devices.NewTCPOscDevice("127.0.0.1", port, tc.framing, &exactDispatcher{handlers: make(map[string][]func(*osc.Message))})
+			blobs := make(chan []byte, 1)
+			d.BindBlob("/blob", func(v []byte) error { blobs <- v; return nil })
+
+			assert.ErrorIs(d.SetInt("/early", 1), devices.ErrOscNotConnected)
+			done := make(chan error)
+			go func() { done <- d.Run() }()
+
+			accept := func() net.Conn {
+				select {
+				case conn := <-conns:
+					return conn
+				case <-time.After(2 * time.Second):
+					t.Fatal("device did not connect")
+					return nil
+				}
+			}
+			exchange := func(conn net.Conn) {
+				data, err := osc.NewMessage("/blob", payload).MarshalBinary()
+				assert.NoError(err)
+				_, err = conn.Write(tc.encode(data))
+				assert.NoError(err)
+				select {
+				case got := <-blobs:
+					assert.Equal(payload, got)
+				case <-time.After(time.Second):
+					t.Fatal("blob was not dispatched")
+				}
+
+				assert.Eventually(d.Connected, time.Second, 5*time.Millisecond)
+				assert.NoError(d.SetArgs("/reply", payload))
+				conn.SetReadDeadline(time.Now().Add(time.Second))
+				data, err = tc.decode(bufio.NewReader(conn))
+				assert.NoError(err)
+				packet, err := osc.ParsePacket(string(data))
+				assert.NoError(err)
+				assert.Equal(osc.NewMessage("/reply", payload), packet)
+			}
+
+			conn := accept()
+			exchange(conn)
+
+			// The device reconnects after the server drops the connection.
+			conn.Close()
+			conn = accept()
+			defer conn.Close()
+			exchange(conn)
+
+			assert.NoError(d.Close())
+			select {
+			case err := <-done:
+				assert.NoError(err)
+			case <-time.After(time.Second):
+				t.Fatal("Run did not return after Close")
+			}
+			assert.False(d.Connected())
+		})
+	}
+}
+
+func slipEncode(data []byte) []byte {
+	frame := []byte{0xC0}
+	for _, b := range data {
+		switch b {
+		case 0xC0:
+			frame = append(frame, 0xDB, 0xDC)
+		case 0xDB:
+			frame = append(frame, 0xDB, 0xDD)
+		default:
+			frame = append(frame, b)
+		}
+	}
+	return append(frame, 0xC0)
+}
+
+func slipDecode(r *bufio.Reader) ([]byte, error) {
+	var data []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case b == 0xC0 && len(data) > 0:
+			return data, nil
+		case b == 0xC0:
+		case b == 0xDB:
+			next, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, map[byte]byte{0xDC: 0xC0, 0xDD: 0xDB}[next])
+		default:
+			data = append(data, b)
+		}
+	}
+}
+
+func lengthEncode(data []byte) []byte {
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(data))), data...)
+}
+
+func lengthDecode(r *bufio.Reader) ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	data := make([]byte, binary.BigEndian.Uint32(size[:]))
+	_, err := io.ReadFull(r, data)
+	return data, err
+}
@@ -0,0 +1,96 @@
+package devices
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// oscImmediately is the OSC timetag meaning "process as soon as received".
+const oscImmediately uint64 = 1
+
+// OscTx collects Set calls and sends them to the client together as a single OSC bundle.
+//
+// OscTx has the same Set methods as OscDevice, so code that writes to a device can write to a transaction instead.
+// Nothing is sent until Commit. Receivers that honor bundles apply all of its messages at once, e.g. a whole bank
+// change lands atomically instead of as dozens of separate updates.
+type OscTx struct {
+	o *OscDevice
+
+	mu     sync.Mutex
+	bundle *osc.Bundle
+}
+
+// Begin starts a transaction whose bundle is to be applied as soon as it is received.
+func (o *OscDevice) Begin() *OscTx {
+	return o.BeginAt(time.Time{})
+}
+
+// BeginAt starts a transaction whose bundle is to be applied at the given time. A zero time means immediately.
+func (o *OscDevice) BeginAt(at time.Time) *OscTx {
+	bundle := &osc.Bundle{Timetag: *osc.NewTimetagFromTimetag(oscImmediately)}
+	if !at.IsZero() {
+		bundle = osc.NewBundle(at)
+	}
+	return &OscTx{o: o, bundle: bundle}
+}
+
+// Batch runs fn with a new transaction and commits it if fn succeeds. If fn returns an error nothing is sent.
+func (o *OscDevice) Batch(fn func(tx *OscTx) error) error {
+	tx := o.Begin()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (tx *OscTx) append(key string, args ...any) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	return tx.bundle.Append(osc.NewMessage(key, args...))
+}
+
+func (tx *OscTx) SetInt(key string, val int64) error {
+	return tx.append(key, int32(val))
+}
+
+func (tx *OscTx) SetFloat(key string, val float64) error {
+	return tx.append(key, float32(val))
+}
+
+func (tx *OscTx) SetString(key string, val string) error {
+	return tx.append(key, val)
+}
+
+func (tx *OscTx) SetBool(key string, val bool) error {
+	return tx.append(key, val)
+}
+
+// SetArgs adds a message carrying all of args to the transaction.
+func (tx *OscTx) SetArgs(key string, args ...any) error {
+	return tx.append(key, args...)
+}
+
+// Len returns the number of messages collected so far.
+func (tx *OscTx) Len() int {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	return len(tx.bundle.Messages)
+}
+
+// Commit sends every collected message as one bundle and empties the transaction so it can be reused. Committing
+// an empty transaction sends nothing.
+func (tx *OscTx) Commit() error {
+	tx.mu.Lock()
+	bundle := tx.bundle
+	tx.bundle = &osc.Bundle{Timetag: bundle.Timetag}
+	tx.mu.Unlock()
+
+	if len(bundle.Messages) == 0 {
+		return nil
+	}
+	oscOutLog.Debug("Sending OSC bundle", slog.Int("messages", len(bundle.Messages)), slog.Time("timetag", bundle.Timetag.Time()))
+	return tx.o.Client.Send(bundle)
+}
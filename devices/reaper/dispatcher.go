@@ -2,7 +2,7 @@ package reaper
 
 import (
 	"log/slog"
-	"strings"
+	"slices"
 	"sync"
 	stdTime "time"
 
@@ -16,65 +16,167 @@ func init() {
 	oscInLog = logging.Get(logging.OSC_IN)
 }
 
-type namedHandler struct {
-	name    string
-	handler func(*osc.Message)
+// Propagation tells the Dispatcher whether a message should reach the remaining handlers.
+type Propagation bool
+
+const (
+	// Continue lets the message reach the remaining handlers.
+	Continue Propagation = false
+	// Stop swallows the message so that no further handlers see it.
+	Stop Propagation = true
+)
+
+// Handler processes a message and reports whether it should propagate to the remaining handlers.
+type Handler func(*osc.Message) Propagation
+
+type entry struct {
+	id       int
+	priority int
+	addr     string
+	// rest holds the segments of the pattern after the literal prefix that places it in the trie.
+	rest    []segment
+	handler Handler
+}
+
+// before reports whether e runs before other: higher priorities first, then in registration order.
+func (e *entry) before(other *entry) int {
+	if e.priority != other.priority {
+		return other.priority - e.priority
+	}
+	return e.id - other.id
+}
+
+// trieNode holds the handlers whose patterns begin with the literal segments on the path to it.
+type trieNode struct {
+	children map[string]*trieNode
+	// exact handlers match only the address ending at this node.
+	exact []*entry
+	// wild handlers continue with wildcards, matched against the rest of the address.
+	wild []*entry
+}
+
+func (n *trieNode) empty() bool {
+	return len(n.children) == 0 && len(n.exact) == 0 && len(n.wild) == 0
 }
 
 // Dispatcher is a custom osc.Dispatcher, implementing the osc.Dispatcher interface
+//
+// Handlers are registered for OSC address patterns (see addrPattern) and indexed in a trie by the literal segments
+// their patterns begin with, so a message is only tested against the wildcard handlers along its own path.
+//
+// Handlers for a message run in order of decreasing priority and, within a priority, in registration order. A
+// handler returning Stop prevents the remaining handlers from seeing the message.
 type Dispatcher struct {
-	mu       sync.RWMutex
-	handlers map[int]namedHandler
-	nextID   int
+	mu     sync.RWMutex
+	root   *trieNode
+	nextID int
 }
 
 func NewDispatcher() *Dispatcher {
-	return &Dispatcher{handlers: make(map[int]namedHandler)}
+	return &Dispatcher{root: &trieNode{}}
 }
 
+// AddMsgHandler registers handler at priority 0 to run for every message whose address matches addr and returns
+// a function that unregisters it. Invalid patterns are logged and never match.
 func (s *Dispatcher) AddMsgHandler(addr string, handler func(*osc.Message)) func() {
+	return s.AddHandler(addr, 0, func(msg *osc.Message) Propagation {
+		handler(msg)
+		return Continue
+	})
+}
+
+// AddHandler registers handler at the given priority to run for every message whose address matches addr and
+// returns a function that unregisters it. Invalid patterns are logged and never match.
+func (s *Dispatcher) AddHandler(addr string, priority int, handler Handler) func() {
+	pattern, err := compilePattern(addr)
+	if err != nil {
+		oscInLog.Error("Failed to add OSC handler", slog.Any("err", err))
+		return func() {}
+	}
+	prefix, rest := pattern.literalPrefix()
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	id := s.nextID
+	e := &entry{id: s.nextID, priority: priority, addr: addr, rest: rest, handler: handler}
 	s.nextID++
-	s.handlers[id] = namedHandler{addr, handler}
+	n := s.root
+	for _, seg := range prefix {
+		child, ok := n.children[seg]
+		if !ok {
+			if n.children == nil {
+				n.children = make(map[string]*trieNode)
+			}
+			child = &trieNode{}
+			n.children[seg] = child
+		}
+		n = child
+	}
+	if len(rest) == 0 {
+		n.exact = append(n.exact, e)
+	} else {
+		n.wild = append(n.wild, e)
+	}
+
+	var once sync.Once
 	return func() {
-		s.mu.Lock()
-		defer s.mu.Unlock()
-		delete(s.handlers, id)
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.remove(s.root, prefix, e)
+		})
 	}
 }
 
-// matchAddr checks if messageAddr matches the path pattern.
-// Any * is treated as a wildcard. Paths are also allowed to end with a "*",
-func matchAddr(path, messageAddr string) bool {
-	pathSegs := strings.Split(path, "/")
-	addrSegs := strings.Split(messageAddr, "/")
-
-	endsWithStar := len(pathSegs) > 0 && pathSegs[len(pathSegs)-1] == "*"
-	matchLen := len(pathSegs)
-	if endsWithStar {
-		// Remove the "*" for matching; allow extra segments in addrSegs
-		matchLen--
-		if len(addrSegs) < matchLen {
-			return false
+// remove deletes e from the node at path below n, pruning nodes left empty. The caller must hold s.mu.
+func (s *Dispatcher) remove(n *trieNode, path []string, e *entry) {
+	if len(path) == 0 {
+		isEntry := func(other *entry) bool { return other == e }
+		n.exact = slices.DeleteFunc(n.exact, isEntry)
+		n.wild = slices.DeleteFunc(n.wild, isEntry)
+		return
+	}
+	child, ok := n.children[path[0]]
+	if !ok {
+		return
+	}
+	s.remove(child, path[1:], e)
+	if child.empty() {
+		delete(n.children, path[0])
+	}
+}
+
+// handlersFor returns the handlers whose patterns match addr, in the order they should run. Handlers run after the
+// lock is released so that they may themselves add or remove handlers.
+func (s *Dispatcher) handlersFor(addr string) []*entry {
+	parts := splitAddr(addr)
+
+	s.mu.RLock()
+	var matched []*entry
+	n := s.root
+	for i := 0; n != nil; i++ {
+		for _, e := range n.wild {
+			if matchSegs(e.rest, parts[i:]) {
+				matched = append(matched, e)
+			}
 		}
-	} else {
-		if len(pathSegs) != len(addrSegs) {
-			return false
+		if i == len(parts) {
+			matched = append(matched, n.exact...)
+			break
 		}
+		n = n.children[parts[i]]
 	}
+	s.mu.RUnlock()
 
-	for i := 0; i < matchLen; i++ {
-		p := pathSegs[i]
-		if p == "*" {
-			continue
-		}
-		if p != addrSegs[i] {
-			return false
+	slices.SortFunc(matched, (*entry).before)
+	return matched
+}
+
+func (s *Dispatcher) dispatchMessage(msg *osc.Message) {
+	for _, e := range s.handlersFor(msg.Address) {
+		if e.handler(msg) == Stop {
+			return
 		}
 	}
-	return true
 }
 
 // Dispatch dispatches OSC packets. Implements the Dispatcher interface.
@@ -85,13 +187,7 @@ func (s *Dispatcher) Dispatch(packet osc.Packet) {
 
 	case *osc.Message:
 		oscInLog.Debug("Received OSC message", slog.String("address", p.Address), slog.Any("arguments", p.Arguments))
-		for _, namedHandler := range s.handlers {
-			if matchAddr(namedHandler.name, p.Address) {
-				s.mu.RLock()
-				namedHandler.handler(p)
-				s.mu.RUnlock()
-			}
-		}
+		s.dispatchMessage(p)
 
 	case *osc.Bundle:
 		timer := stdTime.NewTimer(p.Timetag.ExpiresIn())
@@ -99,13 +195,7 @@ func (s *Dispatcher) Dispatch(packet osc.Packet) {
 		go func() {
 			<-timer.C
 			for _, message := range p.Messages {
-				for _, namedHandler := range s.handlers {
-					if matchAddr(namedHandler.name, message.Address) {
-						s.mu.RLock()
-						namedHandler.handler(message)
-						s.mu.RUnlock()
-					}
-				}
+				s.dispatchMessage(message)
 			}
 
 			// Process all bundles
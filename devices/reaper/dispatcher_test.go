@@ -0,0 +1,137 @@
+package reaper
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hypebeast/go-osc/osc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatternMatch(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		match   []string
+		noMatch []string
+	}{
+		{"/track/volume", []string{"/track/volume"}, []string{"/track/volumes", "/track", "/track/volume/db"}},
+		{"/track/?/mute", []string{"/track/1/mute", "/track/a/mute"}, []string{"/track/10/mute", "/track//mute"}},
+		{"/track/*/volume", []string{"/track/1/volume", "/track/abc/volume"}, []string{"/track/1/2/volume"}},
+		{"/fx/eq*band", []string{"/fx/eqband", "/fx/eq_lo_band"}, []string{"/fx/eqbands"}},
+		{"/ch/[0-3]/solo", []string{"/ch/0/solo", "/ch/3/solo"}, []string{"/ch/4/solo", "/ch/a/solo"}},
+		{"/ch/[abc]", []string{"/ch/a", "/ch/c"}, []string{"/ch/d"}},
+		{"/ch/[!0-9]", []string{"/ch/x"}, []string{"/ch/5"}},
+		{"/ch/[a-]", []string{"/ch/a", "/ch/-"}, []string{"/ch/b"}},
+		{"/track/*/send/{0,1,2}/volume", []string{"/track/g/send/0/volume", "/track/g/send/2/volume"},
+			[]string{"/track/g/send/3/volume", "/track/g/send/01/volume"}},
+		{"/{mute,solo}*", []string{"/mute", "/solo_all"}, []string{"/rec"}},
+		{"//volume", []string{"/volume", "/track/volume", "/track/1/send/2/volume"}, []string{"/track/volume/db"}},
+		{"/track//mute", []string{"/track/mute", "/track/1/mute"}, []string{"/master/mute"}},
+		// A trailing "*" segment also matches deeper addresses.
+		{"/track/*", []string{"/track/1", "/track/1/volume"}, []string{"/track", "/master/1"}},
+		{"*", []string{"/anything", "/a/b/c"}, nil},
+	} {
+		p, err := compilePattern(tc.pattern)
+		if !assert.NoError(t, err, tc.pattern) {
+			continue
+		}
+		for _, addr := range tc.match {
+			assert.True(t, p.match(addr), "%s should match %s", tc.pattern, addr)
+		}
+		for _, addr := range tc.noMatch {
+			assert.False(t, p.match(addr), "%s should not match %s", tc.pattern, addr)
+		}
+	}
+
+	for _, bad := range []string{"/ch/[0-3", "/ch/{a,b", "/ch/]", "/ch/[]", "/ch/[9-0]"} {
+		_, err := compilePattern(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	assert := assert.New(t)
+
+	d := NewDispatcher()
+	var got []string
+	d.AddMsgHandler("/track/1/volume", func(msg *osc.Message) { got = append(got, "exact") })
+	unbind := d.AddMsgHandler("/track/[0-9]/volume", func(msg *osc.Message) { got = append(got, "pattern") })
+	d.AddMsgHandler("/track/[", func(msg *osc.Message) { got = append(got, "invalid") })
+
+	d.Dispatch(osc.NewMessage("/track/1/volume"))
+	assert.Equal([]string{"exact", "pattern"}, got)
+
+	got = nil
+	unbind()
+	d.Dispatch(osc.NewMessage("/track/1/volume"))
+	d.Dispatch(osc.NewMessage("/track/2/volume"))
+	assert.Equal([]string{"exact"}, got)
+
+	// Handlers may register further handlers while running.
+	d.AddMsgHandler("/new", func(msg *osc.Message) {
+		d.AddMsgHandler("/new/child", func(*osc.Message) {})
+	})
+	assert.NotPanics(func() { d.Dispatch(osc.NewMessage("/new")) })
+}
+
+func TestDispatcherOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	d := NewDispatcher()
+	var got []string
+	record := func(name string, prop Propagation) Handler {
+		return func(*osc.Message) Propagation {
+			got = append(got, name)
+			return prop
+		}
+	}
+	d.AddMsgHandler("/track/*", func(*osc.Message) { got = append(got, "default 1") })
+	d.AddMsgHandler("//volume", func(*osc.Message) { got = append(got, "default 2") })
+	d.AddMsgHandler("/track/1/volume", func(*osc.Message) { got = append(got, "default 3") })
+	d.AddHandler("/track/[0-9]/volume", -1, record("fallback", Continue))
+	unbindMode := d.AddHandler("/track/1/volume", 10, record("mode", Stop))
+	d.AddHandler("/track/{1,2}/volume", 20, record("meter", Continue))
+
+	d.Dispatch(osc.NewMessage("/track/1/volume"))
+	assert.Equal([]string{"meter", "mode"}, got, "a handler returning Stop must swallow the message")
+
+	got = nil
+	d.Dispatch(osc.NewMessage("/track/2/volume"))
+	assert.Equal([]string{"meter", "default 1", "default 2", "fallback"}, got)
+
+	got = nil
+	unbindMode()
+	unbindMode()
+	d.Dispatch(osc.NewMessage("/track/1/volume"))
+	assert.Equal([]string{"meter", "default 1", "default 2", "default 3", "fallback"}, got,
+		"equal priorities must run in registration order")
+}
+
+func TestDispatcherPrune(t *testing.T) {
+	d := NewDispatcher()
+	unbind := []func(){
+		d.AddMsgHandler("/track/abc/volume", func(*osc.Message) {}),
+		d.AddMsgHandler("/track/abc/*", func(*osc.Message) {}),
+	}
+	for _, u := range unbind {
+		u()
+	}
+	assert.True(t, d.root.empty(), "removing the last handler should prune its branch")
+}
+
+func BenchmarkDispatch(b *testing.B) {
+	d := NewDispatcher()
+	for i := 0; i < 64; i++ {
+		track := fmt.Sprintf("/track/%d", i)
+		for _, param := range []string{"volume", "pan", "mute", "solo", "name", "select"} {
+			d.AddMsgHandler(track+"/"+param, func(*osc.Message) {})
+		}
+	}
+	d.AddMsgHandler("/track/*", func(*osc.Message) {})
+	msg := osc.NewMessage("/track/42/volume", float32(0.5))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.Dispatch(msg)
+	}
+}
@@ -0,0 +1,273 @@
+package reaper
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// addrPattern is a compiled OSC address pattern.
+//
+// Within a segment the OSC 1.0 grammar applies: '?' matches any single character, '*' any run of characters,
+// "[abc]", "[a-z]" and "[!x]" a character from (or not from) a set, and "{foo,bar}" any of the listed strings.
+// Between segments, "//" matches any number of intermediate segments, as in OSC 1.1.
+//
+// For compatibility with existing handlers, a final segment of exactly "*" matches one or more trailing
+// segments, so "/track/*" matches "/track/3" as well as "/track/3/volume".
+type addrPattern struct {
+	raw  string
+	segs []segment
+	// literal is set when the pattern contains no wildcards, so it can only match itself.
+	literal bool
+}
+
+type segKind uint8
+
+const (
+	segMatch   segKind = iota // matches exactly one segment against toks
+	segDescend                // "//": matches zero or more segments
+	segRest                   // trailing "*": matches one or more segments
+)
+
+type segment struct {
+	kind segKind
+	toks []token
+}
+
+type tokKind uint8
+
+const (
+	tokLiteral tokKind = iota
+	tokAnyChar
+	tokAnyString
+	tokClass
+	tokAlt
+)
+
+type token struct {
+	kind   tokKind
+	lit    string
+	alts   []string
+	ranges []charRange
+	negate bool
+}
+
+type charRange struct {
+	lo, hi rune
+}
+
+// compilePattern parses an OSC address pattern.
+func compilePattern(pattern string) (*addrPattern, error) {
+	p := &addrPattern{raw: pattern, literal: true}
+	parts := splitAddr(pattern)
+	for i, part := range parts {
+		switch {
+		case part == "" && i < len(parts)-1:
+			p.literal = false
+			if i > 0 && parts[i-1] == "" {
+				// "///" adds nothing over "//"
+				continue
+			}
+			p.segs = append(p.segs, segment{kind: segDescend})
+		case part == "*" && i == len(parts)-1:
+			p.literal = false
+			p.segs = append(p.segs, segment{kind: segRest})
+		default:
+			toks, err := compileSegment(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid OSC address pattern %q: %w", pattern, err)
+			}
+			seg := segment{kind: segMatch, toks: toks}
+			if _, ok := seg.literal(); !ok {
+				p.literal = false
+			}
+			p.segs = append(p.segs, seg)
+		}
+	}
+	return p, nil
+}
+
+// splitAddr splits an address into its segments, ignoring the leading '/'.
+func splitAddr(addr string) []string {
+	return strings.Split(strings.TrimPrefix(addr, "/"), "/")
+}
+
+func compileSegment(s string) ([]token, error) {
+	var toks []token
+	var lit strings.Builder
+	flush := func() {
+		if lit.Len() > 0 {
+			toks = append(toks, token{kind: tokLiteral, lit: lit.String()})
+			lit.Reset()
+		}
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '?':
+			flush()
+			toks = append(toks, token{kind: tokAnyChar})
+		case '*':
+			flush()
+			// Consecutive stars are equivalent to one.
+			if len(toks) == 0 || toks[len(toks)-1].kind != tokAnyString {
+				toks = append(toks, token{kind: tokAnyString})
+			}
+		case '[':
+			end := strings.IndexByte(s[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '[' at offset %d", i)
+			}
+			flush()
+			tok, err := compileClass(s[i+1 : i+1+end])
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, tok)
+			i += end + 1
+		case '{':
+			end := strings.IndexByte(s[i+1:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '{' at offset %d", i)
+			}
+			flush()
+			toks = append(toks, token{kind: tokAlt, alts: strings.Split(s[i+1:i+1+end], ",")})
+			i += end + 1
+		case ']', '}':
+			return nil, fmt.Errorf("unexpected %q at offset %d", c, i)
+		default:
+			lit.WriteByte(c)
+		}
+	}
+	flush()
+	return toks, nil
+}
+
+// compileClass parses the inside of a "[...]" expression. A leading '!' negates the set and a '-' between two
+// characters denotes a range; a '-' at either end is literal.
+func compileClass(s string) (token, error) {
+	tok := token{kind: tokClass}
+	if strings.HasPrefix(s, "!") {
+		tok.negate = true
+		s = s[1:]
+	}
+	if s == "" {
+		return tok, fmt.Errorf("empty character class")
+	}
+	chars := []rune(s)
+	for i := 0; i < len(chars); i++ {
+		lo := chars[i]
+		if i+2 < len(chars) && chars[i+1] == '-' {
+			hi := chars[i+2]
+			if hi < lo {
+				return tok, fmt.Errorf("invalid range %c-%c", lo, hi)
+			}
+			tok.ranges = append(tok.ranges, charRange{lo, hi})
+			i += 2
+			continue
+		}
+		tok.ranges = append(tok.ranges, charRange{lo, lo})
+	}
+	return tok, nil
+}
+
+func (t *token) inClass(r rune) bool {
+	for _, cr := range t.ranges {
+		if r >= cr.lo && r <= cr.hi {
+			return !t.negate
+		}
+	}
+	return t.negate
+}
+
+// literalPrefix splits the pattern into its leading wildcard-free segments and the segments that follow.
+func (p *addrPattern) literalPrefix() ([]string, []segment) {
+	var prefix []string
+	for i, seg := range p.segs {
+		lit, ok := seg.literal()
+		if !ok {
+			return prefix, p.segs[i:]
+		}
+		prefix = append(prefix, lit)
+	}
+	return prefix, nil
+}
+
+// literal returns the text of a segment without wildcards.
+func (seg *segment) literal() (string, bool) {
+	switch {
+	case seg.kind != segMatch:
+		return "", false
+	case len(seg.toks) == 0:
+		return "", true
+	case len(seg.toks) == 1 && seg.toks[0].kind == tokLiteral:
+		return seg.toks[0].lit, true
+	default:
+		return "", false
+	}
+}
+
+// match reports whether addr matches the pattern.
+func (p *addrPattern) match(addr string) bool {
+	if p.literal {
+		return p.raw == addr
+	}
+	return matchSegs(p.segs, splitAddr(addr))
+}
+
+func matchSegs(segs []segment, parts []string) bool {
+	if len(segs) == 0 {
+		return len(parts) == 0
+	}
+	switch seg := segs[0]; seg.kind {
+	case segDescend:
+		for i := 0; i <= len(parts); i++ {
+			if matchSegs(segs[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	case segRest:
+		return len(parts) > 0
+	default:
+		return len(parts) > 0 && matchToks(seg.toks, parts[0]) && matchSegs(segs[1:], parts[1:])
+	}
+}
+
+func matchToks(toks []token, s string) bool {
+	if len(toks) == 0 {
+		return s == ""
+	}
+	switch tok := &toks[0]; tok.kind {
+	case tokLiteral:
+		return strings.HasPrefix(s, tok.lit) && matchToks(toks[1:], s[len(tok.lit):])
+	case tokAnyChar:
+		if s == "" {
+			return false
+		}
+		_, n := utf8.DecodeRuneInString(s)
+		return matchToks(toks[1:], s[n:])
+	case tokClass:
+		if s == "" {
+			return false
+		}
+		r, n := utf8.DecodeRuneInString(s)
+		return tok.inClass(r) && matchToks(toks[1:], s[n:])
+	case tokAlt:
+		for _, alt := range tok.alts {
+			if strings.HasPrefix(s, alt) && matchToks(toks[1:], s[len(alt):]) {
+				return true
+			}
+		}
+		return false
+	default: // tokAnyString
+		if len(toks) == 1 {
+			return true
+		}
+		for i := range s {
+			if matchToks(toks[1:], s[i:]) {
+				return true
+			}
+		}
+		return matchToks(toks[1:], "")
+	}
+}
@@ -10,11 +10,19 @@ import (
 
 type Reaper struct {
 	device *devices.OscDevice
+	Time   *time
+	Scrub  *scrub
 }
 
 func NewReaper(dev *devices.OscDevice) *Reaper {
 	return &Reaper{
 		device: dev,
+		Time: &time{
+			device: dev,
+		},
+		Scrub: &scrub{
+			device: dev,
+		},
 	}
 }
 
@@ -495,3 +503,26 @@ func (ep *trackColor) Set(val int64) error {
 
 	return ep.device.SetInt(addr, val)
 }
+
+type time struct {
+	device *devices.OscDevice
+}
+
+func (ep *time) Bind(callback func(float64) error) func() {
+	addr := "/time"
+	return ep.device.BindFloat(addr, callback)
+}
+
+func (ep *time) Set(val float64) error {
+	addr := "/time"
+	return ep.device.SetFloat(addr, val)
+}
+
+type scrub struct {
+	device *devices.OscDevice
+}
+
+func (ep *scrub) Set(val float64) error {
+	addr := "/scrub"
+	return ep.device.SetFloat(addr, val)
+}
@@ -0,0 +1,156 @@
+package devices
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SysExCaptures holds the values of named fields captured while matching a SysEx pattern.
+type SysExCaptures map[string]uint8
+
+// sysExElem matches a single byte of a SysEx message.
+//
+// A byte b matches if lo <= b&mask <= hi. If name is set, b&^mask is captured under that name (or b itself when
+// mask is 0xFF).
+type sysExElem struct {
+	lo, hi uint8
+	mask   uint8
+	name   string
+}
+
+func (e sysExElem) match(b uint8) bool {
+	v := b & e.mask
+	return v >= e.lo && v <= e.hi
+}
+
+func (e sysExElem) capture(b uint8) uint8 {
+	if e.mask == 0xFF {
+		return b
+	}
+	return b &^ e.mask
+}
+
+// SysExPattern is a compiled pattern that matches the leading bytes of a SysEx message.
+//
+// Patterns are written as whitespace-separated tokens, one per byte, excluding the 0xF0/0xF7 framing bytes:
+//
+//	4C      matches exactly 0x4C
+//	??      matches any byte
+//	10-1F   matches any byte in the inclusive range 0x10 to 0x1F
+//	3?      matches any byte whose high nibble is 3
+//
+// Any token may be prefixed with "name=" to capture the matched byte. For nibble tokens such as "ch=9?" only the
+// wildcard low nibble is captured. For example, "00 00 66 14 dev=?? 01" matches a handshake from any device ID
+// and captures the ID as "dev".
+//
+// A pattern matches any message at least as long as the pattern; trailing bytes are ignored.
+type SysExPattern struct {
+	src   string
+	elems []sysExElem
+}
+
+// CompileSysExPattern parses a SysEx pattern. See SysExPattern for the syntax.
+func CompileSysExPattern(pattern string) (*SysExPattern, error) {
+	p := &SysExPattern{src: pattern}
+	names := make(map[string]struct{})
+	for _, tok := range strings.Fields(pattern) {
+		var name string
+		if i := strings.IndexByte(tok, '='); i >= 0 {
+			name, tok = tok[:i], tok[i+1:]
+			if name == "" {
+				return nil, fmt.Errorf("sysex pattern %q: empty capture name", pattern)
+			}
+			if _, dup := names[name]; dup {
+				return nil, fmt.Errorf("sysex pattern %q: duplicate capture name %q", pattern, name)
+			}
+			names[name] = struct{}{}
+		}
+		e, err := parseSysExElem(tok)
+		if err != nil {
+			return nil, fmt.Errorf("sysex pattern %q: %w", pattern, err)
+		}
+		e.name = name
+		p.elems = append(p.elems, e)
+	}
+	return p, nil
+}
+
+// MustCompileSysExPattern is like CompileSysExPattern but panics if the pattern cannot be parsed.
+func MustCompileSysExPattern(pattern string) *SysExPattern {
+	p, err := CompileSysExPattern(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func parseSysExElem(tok string) (sysExElem, error) {
+	switch {
+	case tok == "??":
+		return sysExElem{lo: 0x00, hi: 0xFF, mask: 0xFF}, nil
+	case len(tok) == 2 && tok[1] == '?':
+		hi, err := strconv.ParseUint(tok[:1], 16, 8)
+		if err != nil {
+			return sysExElem{}, fmt.Errorf("invalid nibble token %q", tok)
+		}
+		return sysExElem{lo: uint8(hi << 4), hi: uint8(hi << 4), mask: 0xF0}, nil
+	case strings.Contains(tok, "-"):
+		loStr, hiStr, _ := strings.Cut(tok, "-")
+		lo, err := strconv.ParseUint(loStr, 16, 8)
+		if err != nil {
+			return sysExElem{}, fmt.Errorf("invalid range token %q", tok)
+		}
+		hi, err := strconv.ParseUint(hiStr, 16, 8)
+		if err != nil {
+			return sysExElem{}, fmt.Errorf("invalid range token %q", tok)
+		}
+		if lo > hi {
+			return sysExElem{}, fmt.Errorf("invalid range token %q: low bound exceeds high bound", tok)
+		}
+		return sysExElem{lo: uint8(lo), hi: uint8(hi), mask: 0xFF}, nil
+	default:
+		b, err := strconv.ParseUint(tok, 16, 8)
+		if err != nil {
+			return sysExElem{}, fmt.Errorf("invalid byte token %q", tok)
+		}
+		return sysExElem{lo: uint8(b), hi: uint8(b), mask: 0xFF}, nil
+	}
+}
+
+// exactSysExPattern returns a pattern matching exactly the given leading bytes.
+func exactSysExPattern(prefix []byte) *SysExPattern {
+	p := &SysExPattern{
+		src:   byteSliceToHexLiteral(prefix),
+		elems: make([]sysExElem, len(prefix)),
+	}
+	for i, b := range prefix {
+		p.elems[i] = sysExElem{lo: b, hi: b, mask: 0xFF}
+	}
+	return p
+}
+
+// Match reports whether data matches the pattern and returns any captured fields.
+func (p *SysExPattern) Match(data []byte) (SysExCaptures, bool) {
+	if len(data) < len(p.elems) {
+		return nil, false
+	}
+	var captures SysExCaptures
+	for i, e := range p.elems {
+		if !e.match(data[i]) {
+			return nil, false
+		}
+		if e.name != "" {
+			if captures == nil {
+				captures = make(SysExCaptures)
+			}
+			captures[e.name] = e.capture(data[i])
+		}
+	}
+	return captures, true
+}
+
+// String returns the source text of the pattern.
+func (p *SysExPattern) String() string {
+	return p.src
+}
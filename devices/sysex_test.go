@@ -0,0 +1,116 @@
+package devices_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	midi "gitlab.com/gomidi/midi/v2"
+
+	"github.com/jdginn/arpad/devices"
+	devtest "github.com/jdginn/arpad/devices/devicestesting"
+)
+
+func TestSysExPattern(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		data     []byte
+		matches  bool
+		captures devices.SysExCaptures
+	}{
+		{
+			name:    "exact prefix",
+			pattern: "00 00 66 14",
+			data:    []byte{0x00, 0x00, 0x66, 0x14, 0x01},
+			matches: true,
+		},
+		{
+			name:    "exact mismatch",
+			pattern: "00 00 66 14",
+			data:    []byte{0x00, 0x00, 0x66, 0x15},
+			matches: false,
+		},
+		{
+			name:    "too short",
+			pattern: "00 00 66 14",
+			data:    []byte{0x00, 0x00},
+			matches: false,
+		},
+		{
+			name:    "any byte",
+			pattern: "00 ?? 66",
+			data:    []byte{0x00, 0x7F, 0x66},
+			matches: true,
+		},
+		{
+			name:    "byte range",
+			pattern: "10-1F",
+			data:    []byte{0x1A},
+			matches: true,
+		},
+		{
+			name:    "byte range miss",
+			pattern: "10-1F",
+			data:    []byte{0x20},
+			matches: false,
+		},
+		{
+			name:     "captured device id",
+			pattern:  "00 00 66 dev=?? 01",
+			data:     []byte{0x00, 0x00, 0x66, 0x15, 0x01},
+			matches:  true,
+			captures: devices.SysExCaptures{"dev": 0x15},
+		},
+		{
+			name:     "captured channel nibble",
+			pattern:  "F0-F7 ch=9?",
+			data:     []byte{0xF2, 0x93},
+			matches:  true,
+			captures: devices.SysExCaptures{"ch": 0x03},
+		},
+		{
+			name:    "nibble miss",
+			pattern: "ch=9?",
+			data:    []byte{0x83},
+			matches: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := devices.CompileSysExPattern(tt.pattern)
+			assert.NoError(t, err)
+			captures, ok := p.Match(tt.data)
+			assert.Equal(t, tt.matches, ok)
+			assert.Equal(t, tt.captures, captures)
+		})
+	}
+}
+
+func TestSysExPatternErrors(t *testing.T) {
+	for _, pattern := range []string{"GG", "1F-10", "=00", "a=00 a=01", "100", "x?"} {
+		_, err := devices.CompileSysExPattern(pattern)
+		assert.Error(t, err, "pattern %q should not compile", pattern)
+	}
+}
+
+func TestSysExBindCaptures(t *testing.T) {
+	assert := assert.New(t)
+
+	in := devtest.NewMockMIDIPort()
+	out := devtest.NewMockMIDIPort()
+	d := devices.NewMidiDevice(in, out)
+	defer devtest.RunDevice(t, d.Run, d.IsConnected)()
+
+	var got devices.SysExCaptures
+	var raw []byte
+	d.SysEx.MatchPattern(devices.MustCompileSysExPattern("00 20 32 id=?? 4C strip=00-07")).BindCaptures(
+		func(data []byte, c devices.SysExCaptures) error {
+			raw, got = data, c
+			return nil
+		})
+
+	in.SimulateReceive(midi.SysEx([]byte{0x00, 0x20, 0x32, 0x14, 0x4C, 0x03, 0x41}))
+	assert.Equal(devices.SysExCaptures{"id": 0x14, "strip": 0x03}, got)
+	assert.Equal([]byte{0x00, 0x20, 0x32, 0x14, 0x4C, 0x03, 0x41}, raw)
+}
@@ -0,0 +1,48 @@
+package transform
+
+import "math"
+
+// Number is any type endpoints exchange numeric values as.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// To returns a function that converts raw endpoint values with t.Forward.
+func To[T Number](t Transform) func(T) float64 {
+	return func(v T) float64 {
+		return t.Forward(float64(v))
+	}
+}
+
+// From returns a function that converts values back to the endpoint's type with t.Inverse, rounding to the nearest
+// integer for integer types. Chain a Clamp to keep results within the type's range.
+func From[T Number](t Transform) func(float64) T {
+	half := 0.5
+	integer := T(half) == 0
+	return func(v float64) T {
+		v = t.Inverse(v)
+		if integer {
+			v = math.Round(v)
+		}
+		return T(v)
+	}
+}
+
+// Callback adapts a callback taking transformed values so it can be bound to an endpoint reporting raw values.
+func Callback[T Number](t Transform, callback func(float64) error) func(T) error {
+	to := To[T](t)
+	return func(v T) error {
+		return callback(to(v))
+	}
+}
+
+// Setter adapts an endpoint's Set method so it can be called with transformed values, converting them back with
+// t.Inverse.
+func Setter[T Number](t Transform, set func(T) error) func(float64) error {
+	from := From[T](t)
+	return func(v float64) error {
+		return set(from(v))
+	}
+}
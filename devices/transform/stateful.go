@@ -0,0 +1,79 @@
+package transform
+
+import (
+	"math"
+	"sync"
+)
+
+// Deadband suppresses changes smaller than its width, so a noisy control does not send a stream of tiny updates.
+// It is stateful, so use one per control.
+type Deadband struct {
+	width float64
+
+	mu   sync.Mutex
+	last float64
+	has  bool
+}
+
+// NewDeadband returns a Deadband that passes a value on only once it differs from the last value passed by at
+// least width.
+func NewDeadband(width float64) *Deadband {
+	return &Deadband{width: width}
+}
+
+// Forward returns v if it has moved far enough from the last value passed, and that last value otherwise.
+func (d *Deadband) Forward(v float64) float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.has && math.Abs(v-d.last) < d.width {
+		return d.last
+	}
+	d.last, d.has = v, true
+	return v
+}
+
+// Inverse passes feedback through unchanged and takes it as the new reference for Forward.
+func (d *Deadband) Inverse(v float64) float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.last, d.has = v, true
+	return v
+}
+
+// Relative turns relative steps, such as encoder clicks, into an absolute value bounded to [min, max]. It is
+// stateful, so use one per control.
+type Relative struct {
+	min, max, step float64
+
+	mu    sync.Mutex
+	value float64
+}
+
+// NewRelative returns a Relative starting at initial, moving by step for each unit of input.
+func NewRelative(min, max, step, initial float64) *Relative {
+	return &Relative{min: min, max: max, step: step, value: math.Min(math.Max(initial, min), max)}
+}
+
+// Forward moves the value by delta steps and returns the new absolute value.
+func (r *Relative) Forward(delta float64) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.value = math.Min(math.Max(r.value+delta*r.step, r.min), r.max)
+	return r.value
+}
+
+// Inverse takes an absolute value reported by the other side as the new position. Feedback has no relative
+// representation, so it returns a step of zero.
+func (r *Relative) Inverse(v float64) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.value = math.Min(math.Max(v, r.min), r.max)
+	return 0
+}
+
+// Value returns the current absolute value.
+func (r *Relative) Value() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.value
+}
@@ -0,0 +1,179 @@
+// Package transform converts values between the domains of the endpoints a mapping connects, such as 14-bit fader
+// positions, normalized DAW parameters, decibels and linear gain.
+//
+// Every Transform can be applied in both directions, so a mapping that converts a control's value with Forward
+// converts feedback for that control with Inverse and the two directions cannot drift apart. Transforms compose
+// with Chain:
+//
+//	volume := transform.Chain(transform.Pitchbend14, transform.ReaperVolume)
+//	fader.Bind(transform.Callback(volume, func(gain float64) error { ... }))
+//	track.Volume.Bind(transform.Setter(volume, fader.Set))
+package transform
+
+import (
+	"math"
+	"sort"
+)
+
+// Transform maps values from an input domain to an output domain and back.
+type Transform interface {
+	// Forward maps an input value to the output domain.
+	Forward(float64) float64
+	// Inverse maps an output value back to the input domain.
+	Inverse(float64) float64
+}
+
+// Func builds a Transform from a pair of functions.
+type Func struct {
+	F, I func(float64) float64
+}
+
+func (t Func) Forward(v float64) float64 { return t.F(v) }
+func (t Func) Inverse(v float64) float64 { return t.I(v) }
+
+// Identity leaves values unchanged.
+var Identity Transform = Func{F: identity, I: identity}
+
+func identity(v float64) float64 { return v }
+
+// Chain applies transforms in order going forward and in reverse order going back.
+func Chain(ts ...Transform) Transform {
+	return Func{
+		F: func(v float64) float64 {
+			for _, t := range ts {
+				v = t.Forward(v)
+			}
+			return v
+		},
+		I: func(v float64) float64 {
+			for i := len(ts) - 1; i >= 0; i-- {
+				v = ts[i].Inverse(v)
+			}
+			return v
+		},
+	}
+}
+
+// Invert swaps the directions of t.
+func Invert(t Transform) Transform {
+	return Func{F: t.Inverse, I: t.Forward}
+}
+
+// Linear maps [inMin, inMax] onto [outMin, outMax]. Values outside the input range are extrapolated; follow it
+// with Clamp to bound them.
+func Linear(inMin, inMax, outMin, outMax float64) Transform {
+	scale := (outMax - outMin) / (inMax - inMin)
+	return Func{
+		F: func(v float64) float64 { return outMin + (v-inMin)*scale },
+		I: func(v float64) float64 { return inMin + (v-outMin)/scale },
+	}
+}
+
+// Ranges of common MIDI values, normalized to [0, 1].
+var (
+	// Norm7 maps 7-bit controller values onto [0, 1].
+	Norm7 = Linear(0, 127, 0, 1)
+	// Norm14 maps 14-bit controller values onto [0, 1].
+	Norm14 = Linear(0, 16383, 0, 1)
+	// Pitchbend14 maps pitch bend values, as sent by motor faders, onto [0, 1].
+	Pitchbend14 = Norm14
+)
+
+// Clamp bounds values to [min, max] in both directions. It makes a chain robust to out-of-range input at the cost
+// of exact invertibility outside the range.
+func Clamp(min, max float64) Transform {
+	clamp := func(v float64) float64 { return math.Min(math.Max(v, min), max) }
+	return Func{F: clamp, I: clamp}
+}
+
+// Quantize rounds values to the nearest multiple of step in both directions.
+func Quantize(step float64) Transform {
+	round := func(v float64) float64 { return math.Round(v/step) * step }
+	return Func{F: round, I: round}
+}
+
+// GainToDB maps linear gain to decibels. Gains at or below the gain of floor map to floor, and floor maps back to
+// a gain of zero, so silence survives a round trip.
+func GainToDB(floor float64) Transform {
+	return Func{
+		F: func(gain float64) float64 {
+			if gain <= 0 {
+				return floor
+			}
+			return math.Max(20*math.Log10(gain), floor)
+		},
+		I: func(db float64) float64 {
+			if db <= floor {
+				return 0
+			}
+			return math.Pow(10, db/20)
+		},
+	}
+}
+
+// DBTaper maps positions in [0, 1] linearly onto decibels in [minDB, maxDB]. Position 0 is treated as minDB, which
+// GainToDB(minDB) turns into silence.
+func DBTaper(minDB, maxDB float64) Transform {
+	return Chain(Clamp(0, 1), Linear(0, 1, minDB, maxDB))
+}
+
+// PowerTaper maps positions in [0, 1] to linear gain in [0, maxGain] along gain = maxGain * position^exponent.
+func PowerTaper(exponent, maxGain float64) Transform {
+	return Func{
+		F: func(pos float64) float64 {
+			return maxGain * math.Pow(math.Max(pos, 0), exponent)
+		},
+		I: func(gain float64) float64 {
+			return math.Pow(math.Max(gain, 0)/maxGain, 1/exponent)
+		},
+	}
+}
+
+// ReaperVolume maps normalized fader positions in [0, 1] to linear gain, approximating REAPER's volume fader:
+// +12 dB at the top of its travel and unity gain at about 70% of it.
+var ReaperVolume = PowerTaper(4, 4)
+
+// Point is a breakpoint of a Piecewise transform.
+type Point struct {
+	In, Out float64
+}
+
+// Piecewise interpolates linearly between breakpoints. Points must be strictly increasing in both In and Out so
+// that the transform is invertible; values beyond the first and last points are clamped to them.
+func Piecewise(points ...Point) Transform {
+	interp := func(v float64, in, out func(Point) float64) float64 {
+		i := sort.Search(len(points), func(i int) bool { return in(points[i]) >= v })
+		switch i {
+		case 0:
+			return out(points[0])
+		case len(points):
+			return out(points[len(points)-1])
+		}
+		p, q := points[i-1], points[i]
+		return out(p) + (v-in(p))*(out(q)-out(p))/(in(q)-in(p))
+	}
+	in := func(p Point) float64 { return p.In }
+	out := func(p Point) float64 { return p.Out }
+	return Func{
+		F: func(v float64) float64 { return interp(v, in, out) },
+		I: func(v float64) float64 { return interp(v, out, in) },
+	}
+}
+
+// motuFloorDB is the level below which MOTU faders are treated as off.
+const motuFloorDB = -90
+
+// MotuFader maps normalized fader positions in [0, 1] to the linear gain in [0, 4] used by MOTU's mixer faders. It
+// follows a console-style law: 0 dB at three quarters of travel, +12 dB at the top, finer resolution around unity
+// and the bottom of the travel cutting to silence.
+var MotuFader = Chain(
+	Piecewise(
+		Point{0, motuFloorDB},
+		Point{0.05, -60},
+		Point{0.25, -30},
+		Point{0.5, -12},
+		Point{0.75, 0},
+		Point{1, 20 * math.Log10(4)},
+	),
+	Invert(GainToDB(motuFloorDB)),
+)
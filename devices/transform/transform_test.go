@@ -0,0 +1,89 @@
+package transform
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundTrip(t *testing.T) {
+	for name, tc := range map[string]struct {
+		t      Transform
+		inputs []float64
+	}{
+		"linear":  {Linear(0, 127, -1, 1), []float64{0, 1, 64, 127}},
+		"norm14":  {Norm14, []float64{0, 8192, 16383}},
+		"gain":    {GainToDB(-90), []float64{0.001, 0.5, 1, 4}},
+		"taper":   {DBTaper(-60, 12), []float64{0, 0.5, 1}},
+		"reaper":  {ReaperVolume, []float64{0, 0.25, 0.7, 1}},
+		"motu":    {MotuFader, []float64{0, 0.03, 0.4, 0.75, 1}},
+		"chained": {Chain(Pitchbend14, ReaperVolume, GainToDB(-150)), []float64{3000, 8000, 16383}},
+	} {
+		for _, in := range tc.inputs {
+			assert.InDelta(t, in, tc.t.Inverse(tc.t.Forward(in)), 1e-9, "%s(%v)", name, in)
+		}
+	}
+}
+
+func TestCurves(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.InDelta(4, ReaperVolume.Forward(1), 1e-9, "+12 dB at the top")
+	assert.InDelta(1, ReaperVolume.Forward(math.Sqrt(0.5)), 1e-9)
+
+	assert.InDelta(4, MotuFader.Forward(1), 1e-9)
+	assert.InDelta(1, MotuFader.Forward(0.75), 1e-9, "unity at three quarters of travel")
+	assert.Equal(0.0, MotuFader.Forward(0), "the bottom of the travel is silent")
+	assert.Equal(0.0, MotuFader.Inverse(0))
+	assert.InDelta(-12, GainToDB(-90).Forward(MotuFader.Forward(0.5)), 1e-9)
+	assert.Equal(1.0, MotuFader.Inverse(10), "gains beyond the maximum clamp to the top")
+
+	assert.Equal(-90.0, GainToDB(-90).Forward(0))
+	assert.Equal(0.0, GainToDB(-90).Inverse(-120))
+
+	assert.Equal(1.0, Clamp(0, 1).Forward(2))
+	assert.Equal(0.0, Clamp(0, 1).Inverse(-1))
+	assert.InDelta(0.75, Quantize(0.25).Forward(0.8), 1e-9)
+	assert.Equal(3.0, Invert(Linear(0, 1, 0, 10)).Forward(30))
+	assert.Equal(5.0, Identity.Forward(5))
+}
+
+func TestStateful(t *testing.T) {
+	assert := assert.New(t)
+
+	d := NewDeadband(0.1)
+	assert.Equal(0.5, d.Forward(0.5))
+	assert.Equal(0.5, d.Forward(0.55), "changes within the deadband are suppressed")
+	assert.Equal(0.65, d.Forward(0.65))
+	assert.Equal(0.2, d.Inverse(0.2))
+	assert.Equal(0.2, d.Forward(0.25), "feedback becomes the new reference")
+
+	r := NewRelative(0, 1, 0.1, 0.5)
+	assert.InDelta(0.7, r.Forward(2), 1e-9)
+	assert.InDelta(0.6, r.Forward(-1), 1e-9)
+	assert.Equal(1.0, r.Forward(10), "the value is bounded")
+	assert.Equal(0.0, r.Inverse(0.2))
+	assert.InDelta(0.3, r.Forward(1), 1e-9, "feedback sets the position")
+	assert.InDelta(0.3, r.Value(), 1e-9)
+}
+
+func TestBind(t *testing.T) {
+	assert := assert.New(t)
+
+	var got float64
+	cb := Callback[uint16](Pitchbend14, func(v float64) error { got = v; return nil })
+	assert.NoError(cb(16383))
+	assert.Equal(1.0, got)
+
+	var sent uint16
+	set := Setter(Chain(Pitchbend14, Clamp(0, 1)), func(v uint16) error { sent = v; return nil })
+	assert.NoError(set(0.5))
+	assert.Equal(uint16(8192), sent, "integer endpoints round to the nearest value")
+	assert.NoError(set(2))
+	assert.Equal(uint16(16383), sent)
+
+	assert.Equal(0.25, From[float64](Identity)(0.25))
+	assert.Equal(uint8(127), From[uint8](Norm7)(1))
+	assert.InDelta(0.5, To[uint8](Norm7)(uint8(63))+0.5/127, 1e-9)
+}
@@ -0,0 +1,167 @@
+package xtouch
+
+import (
+	"strings"
+	"unicode"
+)
+
+// abbreviations are the short forms Abbreviate substitutes for common words in track names, keyed by the lower
+// case word.
+var abbreviations = map[string]string{
+	"acoustic":    "Ac",
+	"ambience":    "Amb",
+	"background":  "BG",
+	"backing":     "Bk",
+	"channel":     "Ch",
+	"chorus":      "Chr",
+	"delay":       "Dly",
+	"double":      "Dbl",
+	"drums":       "Drm",
+	"effects":     "FX",
+	"electric":    "El",
+	"group":       "Grp",
+	"guitar":      "Gtr",
+	"guitars":     "Gtrs",
+	"harmony":     "Hrm",
+	"input":       "In",
+	"keyboard":    "Keys",
+	"keyboards":   "Keys",
+	"kick":        "Kik",
+	"lead":        "Ld",
+	"left":        "L",
+	"master":      "Mst",
+	"microphone":  "Mic",
+	"output":      "Out",
+	"overhead":    "OH",
+	"overheads":   "OH",
+	"percussion":  "Perc",
+	"piano":       "Pno",
+	"return":      "Rtn",
+	"reverb":      "Verb",
+	"right":       "R",
+	"room":        "Rm",
+	"snare":       "Snr",
+	"strings":     "Str",
+	"synth":       "Syn",
+	"synthesizer": "Syn",
+	"track":       "Trk",
+	"vocal":       "Vox",
+	"vocals":      "Vox",
+}
+
+// Abbreviate shortens name to fit in width characters while keeping it recognisable. It tries, in order, until the
+// name fits:
+//
+//  1. substituting short forms for common words, e.g. "Vocals" becomes "Vox" and "Double" becomes "Dbl";
+//  2. dropping the spaces between words, capitalising each so the boundaries stay visible;
+//  3. removing vowels from the longest word, never its first letter;
+//  4. trimming letters from the end of the longest word.
+//
+// Words are split at spaces, underscores, hyphens, lower-to-upper case changes and letter-digit changes. Numbers and
+// single letters are never shortened, so "Tom 1" and "Tom 2" stay distinct. If the name is still too long once only
+// they are left, it is cut to fit and ends in "~" to show that it was, e.g. "12345678" becomes "123456~".
+func Abbreviate(name string, width int) string {
+	if len([]rune(name)) <= width {
+		return name
+	}
+	words := splitWords(name)
+	if len(words) == 0 {
+		return ""
+	}
+	for i, w := range words {
+		if short, ok := abbreviations[strings.ToLower(string(w))]; ok {
+			words[i] = []rune(short)
+		}
+	}
+	if s := joinWords(words, " "); len([]rune(s)) <= width {
+		return s
+	}
+	for i, w := range words {
+		words[i][0] = unicode.ToUpper(w[0])
+	}
+	for joinedLen(words) > width {
+		i := longestWord(words)
+		if i < 0 {
+			break
+		}
+		words[i] = shortenWord(words[i])
+	}
+	s := []rune(joinWords(words, ""))
+	if len(s) > width {
+		// Only numbers and single letters are left.
+		if width < 1 {
+			return ""
+		}
+		s = append(s[:width-1], '~')
+	}
+	return string(s)
+}
+
+// splitWords breaks name into words at separators, case changes and letter-digit changes.
+func splitWords(name string) [][]rune {
+	var words [][]rune
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, word)
+			word = nil
+		}
+	}
+	for _, r := range name {
+		if unicode.IsSpace(r) || r == '_' || r == '-' {
+			flush()
+			continue
+		}
+		if len(word) > 0 {
+			prev := word[len(word)-1]
+			if unicode.IsLower(prev) && unicode.IsUpper(r) || unicode.IsDigit(prev) != unicode.IsDigit(r) {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
+
+func joinWords(words [][]rune, sep string) string {
+	parts := make([]string, len(words))
+	for i, w := range words {
+		parts[i] = string(w)
+	}
+	return strings.Join(parts, sep)
+}
+
+// joinedLen is the length of words joined without spaces.
+func joinedLen(words [][]rune) int {
+	n := 0
+	for _, w := range words {
+		n += len(w)
+	}
+	return n
+}
+
+// longestWord returns the index of the longest word that can still be shortened, preferring the last of equally
+// long words, or -1 if every word is a number or a single letter.
+func longestWord(words [][]rune) int {
+	best := -1
+	for i, w := range words {
+		if len(w) < 2 || unicode.IsDigit(w[0]) {
+			continue
+		}
+		if best < 0 || len(w) >= len(words[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+// shortenWord removes the last vowel after the first letter of w or, if there is none, its last letter.
+func shortenWord(w []rune) []rune {
+	for i := len(w) - 1; i > 0; i-- {
+		if strings.ContainsRune("aeiouAEIOU", w[i]) {
+			return append(w[:i:i], w[i+1:]...)
+		}
+	}
+	return w[:len(w)-1]
+}
@@ -1,6 +1,8 @@
 package xtouch
 
 import (
+	"sync"
+
 	dev "github.com/jdginn/arpad/devices"
 )
 
@@ -14,6 +16,11 @@ type Button struct {
 	On  *buttonOn
 	Off *buttonOff
 	LED *led
+
+	// The LED's intended velocity, and whether the surface is believed to show it.
+	ledMu    sync.Mutex
+	ledValue uint8
+	ledShown bool
 }
 
 type buttonOn struct {
@@ -62,7 +69,7 @@ type ledOn struct {
 }
 
 func (l *ledOn) Set() error {
-	return l.d.Note(l.channel, l.key).On.Set(127)
+	return l.setLED(127)
 }
 
 type ledOff struct {
@@ -70,7 +77,7 @@ type ledOff struct {
 }
 
 func (l *ledOff) Set() error {
-	return l.d.Note(l.channel, l.key).On.Set(0)
+	return l.setLED(0)
 }
 
 type ledFlashing struct {
@@ -78,11 +85,52 @@ type ledFlashing struct {
 }
 
 func (l *ledFlashing) SetF() error {
-	return l.d.Note(l.channel, l.key).On.Set(1)
+	return l.setLED(1)
+}
+
+// setLED sends v as the velocity of the button's note, which sets its LED, unless the LED already shows it.
+func (b *Button) setLED(v uint8) error {
+	b.ledMu.Lock()
+	defer b.ledMu.Unlock()
+	if b.ledShown && b.ledValue == v {
+		return nil
+	}
+	b.ledValue = v
+	return b.sendLED()
+}
+
+// sendLED sends the LED's intended velocity. The caller must hold b.ledMu.
+func (b *Button) sendLED() error {
+	err := b.d.Note(b.channel, b.key).On.Set(b.ledValue)
+	b.ledShown = err == nil
+	return err
+}
+
+func (b *Button) refresh() error {
+	b.ledMu.Lock()
+	defer b.ledMu.Unlock()
+	return b.sendLED()
+}
+
+func (b *Button) save() any {
+	b.ledMu.Lock()
+	defer b.ledMu.Unlock()
+	return b.ledValue
+}
+
+func (b *Button) restore(state any) error {
+	return b.setLED(state.(uint8))
 }
 
 // NewButton returns a new button corresponding to the given channel and MIDI key.
 func (x *XTouch) NewButton(channel, key uint8) *Button {
+	b := x.newButton(channel, key)
+	x.track(b)
+	return b
+}
+
+// newButton returns a button whose LED is not tracked, for notes such as fader touches that have no LED.
+func (x *XTouch) newButton(channel, key uint8) *Button {
 	b := &Button{
 		d:       x.base,
 		channel: channel,
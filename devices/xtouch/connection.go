@@ -0,0 +1,92 @@
+package xtouch
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// ConnectionState is the state of the link to the surface as seen by the handshake.
+type ConnectionState uint8
+
+const (
+	// Connecting means the handshake has started but the surface has not yet replied.
+	Connecting ConnectionState = iota
+	// Online means the surface is replying to pings.
+	Online
+	// Lost means the surface stopped replying, or its MIDI ports went away, after having been online.
+	Lost
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case Online:
+		return "online"
+	case Lost:
+		return "lost"
+	default:
+		return "connecting"
+	}
+}
+
+// connection tracks the ConnectionState of a surface and the callbacks bound to it.
+type connection struct {
+	mu        sync.Mutex
+	state     ConnectionState
+	nextID    int
+	callbacks map[int]func(ConnectionState) error
+}
+
+// Connection returns the current state of the link to the surface.
+func (x *XTouch) Connection() ConnectionState {
+	x.conn.mu.Lock()
+	defer x.conn.mu.Unlock()
+	return x.conn.state
+}
+
+// BindConnection specifies the callback to run each time the state of the link to the surface changes.
+func (x *XTouch) BindConnection(callback func(ConnectionState) error) func() {
+	c := &x.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.callbacks == nil {
+		c.callbacks = make(map[int]func(ConnectionState) error)
+	}
+	id := c.nextID
+	c.nextID++
+	c.callbacks[id] = callback
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.callbacks, id)
+	}
+}
+
+// setConnection moves the link to state, running bound callbacks if it changed. Whenever the surface comes online
+// everything it should be showing is pushed to it, since it starts up blank.
+func (x *XTouch) setConnection(state ConnectionState) {
+	c := &x.conn
+	c.mu.Lock()
+	if c.state == state {
+		c.mu.Unlock()
+		return
+	}
+	prev := c.state
+	c.state = state
+	callbacks := make([]func(ConnectionState) error, 0, len(c.callbacks))
+	for _, callback := range c.callbacks {
+		callbacks = append(callbacks, callback)
+	}
+	c.mu.Unlock()
+
+	log.Info("X-Touch connection changed", slog.String("from", prev.String()), slog.String("to", state.String()))
+	if state == Online {
+		if err := x.Refresh(); err != nil {
+			log.Error("failed to restore X-Touch state", slog.Any("err", err))
+		}
+	}
+	for _, callback := range callbacks {
+		if err := callback(state); err != nil {
+			log.Error("connection state callback failed", slog.String("state", state.String()), slog.Any("err", err))
+		}
+	}
+}
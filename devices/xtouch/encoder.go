@@ -3,6 +3,7 @@ package xtouch
 import (
 	"fmt"
 	"math"
+	"sync"
 
 	dev "github.com/jdginn/arpad/devices"
 )
@@ -18,6 +19,41 @@ const (
 	EncoderCounterClockwise EncoderDirection = 65
 )
 
+// RelativeMode selects how an encoder packs a relative turn into a single CC value. Faster turns report more than
+// one step per message, so every mode carries a step count as well as a direction.
+type RelativeMode uint8
+
+const (
+	// SignMagnitude sets bit 6 for counterclockwise turns and carries the number of steps in bits 0-5, so 1 is one
+	// step clockwise and 65 one step counterclockwise. This is what the X-Touch sends.
+	SignMagnitude RelativeMode = iota
+	// TwosComplement carries the steps as a 7-bit two's complement number, so 1 is one step clockwise and 127 one
+	// step counterclockwise.
+	TwosComplement
+	// BinaryOffset carries the steps offset by 64, so 65 is one step clockwise and 63 one step counterclockwise.
+	BinaryOffset
+)
+
+// Decode converts a CC value into a signed number of steps, positive for clockwise turns.
+func (m RelativeMode) Decode(v uint8) int {
+	v &= 0x7F
+	switch m {
+	case TwosComplement:
+		if v&0x40 != 0 {
+			return int(v) - 0x80
+		}
+		return int(v)
+	case BinaryOffset:
+		return int(v) - 0x40
+	default:
+		steps := int(v & 0x3F)
+		if v&0x40 != 0 {
+			return -steps
+		}
+		return steps
+	}
+}
+
 type Encoder struct {
 	d *dev.MidiDevice
 
@@ -25,19 +61,184 @@ type Encoder struct {
 	encoderCC   uint8 // CC 16-23 for encoder rotation
 	ledRingLow  uint8 // For CC 48-55
 	ledRingHigh uint8 // For CC 56-63
+	mode        RelativeMode
 
 	Ring ring
 }
 
-// TODO: binding needs to be a little more carefully thought through...
+// SetMode selects how turns reported by this encoder are decoded. Encoders default to SignMagnitude.
+func (e *Encoder) SetMode(m RelativeMode) *Encoder {
+	e.mode = m
+	return e
+}
+
+// Bind specifies the callback to run with the raw CC value each time the encoder is turned. Most callers want
+// BindDelta or Accumulate instead, which decode the value.
 func (e *Encoder) Bind(callback func(uint8) error) func() {
 	return e.d.CC(e.channel, e.encoderCC).Bind(callback)
 }
 
+// BindDelta specifies the callback to run with the signed number of steps each time the encoder is turned.
+func (e *Encoder) BindDelta(callback func(int) error) func() {
+	return e.Bind(func(v uint8) error {
+		if steps := e.mode.Decode(v); steps != 0 {
+			return callback(steps)
+		}
+		return nil
+	})
+}
+
+// Accumulate applies each turn of the encoder to a and runs callback with the resulting value whenever it changes.
+func (e *Encoder) Accumulate(a *Accumulator, callback func(float64) error) func() {
+	return e.BindDelta(func(steps int) error {
+		if v, changed := a.Add(steps); changed {
+			return callback(v)
+		}
+		return nil
+	})
+}
+
+// defaultFineFactor is how many times finer an Accumulator moves in fine mode unless configured otherwise.
+const defaultFineFactor = 10
+
+// Accumulator turns encoder steps into an absolute value clamped to a range, such as a pan position. In fine mode,
+// e.g. while SHIFT is held, each step moves the value by a fraction of the usual amount.
+type Accumulator struct {
+	min, max       float64
+	step, fineStep float64
+
+	mu    sync.Mutex
+	value float64
+	fine  bool
+}
+
+// NewAccumulator returns an Accumulator over [lo, hi], starting at lo, that takes resolution steps to sweep the
+// whole range. Fine mode takes ten times as many steps.
+func NewAccumulator(lo, hi float64, resolution int) *Accumulator {
+	step := (hi - lo) / float64(max(resolution, 1))
+	return &Accumulator{
+		min:      lo,
+		max:      hi,
+		step:     step,
+		fineStep: step / defaultFineFactor,
+		value:    lo,
+	}
+}
+
+// SetFineResolution sets the number of steps fine mode takes to sweep the whole range.
+func (a *Accumulator) SetFineResolution(resolution int) *Accumulator {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.fineStep = (a.max - a.min) / float64(max(resolution, 1))
+	return a
+}
+
+// FineWhile puts the accumulator in fine mode while button is held.
+func (a *Accumulator) FineWhile(button *Button) *Accumulator {
+	button.On.Bind(func() error {
+		a.SetFine(true)
+		return nil
+	})
+	button.Off.Bind(func() error {
+		a.SetFine(false)
+		return nil
+	})
+	return a
+}
+
+// SetFine turns fine mode on or off.
+func (a *Accumulator) SetFine(fine bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.fine = fine
+}
+
+// Set moves the value to v, clamped to the range, e.g. to follow feedback from the parameter it controls.
+func (a *Accumulator) Set(v float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.value = a.clamp(v)
+}
+
+// Value returns the current value.
+func (a *Accumulator) Value() float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.value
+}
+
+// Add moves the value by the given number of steps and returns the new value along with whether it changed.
+func (a *Accumulator) Add(steps int) (float64, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	step := a.step
+	if a.fine {
+		step = a.fineStep
+	}
+	old := a.value
+	a.value = a.clamp(a.value + float64(steps)*step)
+	return a.value, a.value != old
+}
+
+func (a *Accumulator) clamp(v float64) float64 {
+	return math.Min(math.Max(v, a.min), a.max)
+}
+
 type ring struct {
 	base             *Encoder
 	AllSegments      ringSetAllSegments
 	ClearAllSegments ringClearAllSegments
+
+	// The intended segment patterns, and whether the surface is believed to show them.
+	mu        sync.Mutex
+	low, high uint8
+	shown     bool
+}
+
+// ringState is what an encoder ring shows.
+type ringState struct {
+	low, high uint8
+}
+
+// set lights the segments in low and high, unless the ring already shows them.
+func (e *ring) set(low, high uint8) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.shown && e.low == low && e.high == high {
+		return nil
+	}
+	e.low, e.high = low, high
+	return e.send()
+}
+
+// send sends the intended segment patterns. The caller must hold e.mu.
+func (e *ring) send() error {
+	e.shown = false
+	if err := e.base.d.CC(e.base.channel, e.base.ledRingLow).Set(e.low); err != nil {
+		return fmt.Errorf("failed to set low LED ring value: %v", err)
+	}
+	if err := e.base.d.CC(e.base.channel, e.base.ledRingHigh).Set(e.high); err != nil {
+		return fmt.Errorf("failed to set high LED ring value: %v", err)
+	}
+	e.shown = true
+	return nil
+}
+
+func (e *ring) refresh() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.send()
+}
+
+func (e *ring) save() any {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return ringState{e.low, e.high}
+}
+
+func (e *ring) restore(state any) error {
+	st := state.(ringState)
+	return e.set(st.low, st.high)
 }
 
 // SetLEDRingRelative sets the encoder LED ring based on a relative float value [0.0, 1.0].
@@ -90,15 +291,7 @@ func (e *ring) Set(v float64) error {
 	lowValue = lowPattern[step]
 	highValue = highPattern[step]
 
-	// Send both CC messages
-	if err := e.base.d.CC(e.base.channel, e.base.ledRingLow).Set(lowValue); err != nil {
-		return fmt.Errorf("failed to set low LED ring value: %v", err)
-	}
-	if err := e.base.d.CC(e.base.channel, e.base.ledRingLow).Set(highValue); err != nil {
-		return fmt.Errorf("failed to set low LED ring value: %v", err)
-	}
-
-	return nil
+	return e.set(lowValue, highValue)
 }
 
 type ringSetAllSegments struct {
@@ -108,13 +301,7 @@ type ringSetAllSegments struct {
 func (e *ringSetAllSegments) Set() error {
 	const lowValue uint8 = 0 // TODO: check this
 	const highValue uint8 = 127
-	if err := e.d.CC(e.channel, e.ledRingLow).Set(lowValue); err != nil {
-		return fmt.Errorf("failed to set low LED ring value: %v", err)
-	}
-	if err := e.d.CC(e.channel, e.ledRingHigh).Set(highValue); err != nil {
-		return fmt.Errorf("failed to set low LED ring value: %v", err)
-	}
-	return nil
+	return e.Ring.set(lowValue, highValue)
 }
 
 type ringClearAllSegments struct {
@@ -124,11 +311,5 @@ type ringClearAllSegments struct {
 func (e *ringClearAllSegments) Set() error {
 	const lowValue uint8 = 0
 	const highValue uint8 = 0
-	if err := e.d.CC(e.channel, e.ledRingLow).Set(lowValue); err != nil {
-		return fmt.Errorf("failed to set low LED ring value: %v", err)
-	}
-	if err := e.d.CC(e.channel, e.ledRingHigh).Set(highValue); err != nil {
-		return fmt.Errorf("failed to set low LED ring value: %v", err)
-	}
-	return nil
+	return e.Ring.set(lowValue, highValue)
 }
@@ -0,0 +1,128 @@
+package xtouch
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Unit is a physical surface that can join a SurfaceGroup, such as an XTouchDefault or an XTouchExtender.
+type Unit interface {
+	// Strips returns the unit's channel strips from left to right.
+	Strips() []*channelStrip
+	// Run starts the unit and blocks until ctx is cancelled or the unit fails to start.
+	Run(ctx context.Context) error
+}
+
+// SurfaceGroup joins the channel strips of several units into one wide surface, so that an X-Touch with two
+// extenders can be addressed as strips 0-23.
+//
+// Strips are numbered left to right across units in the group's unit order, which defaults to the order the units
+// were given in and can be changed with Reorder to match how the units sit on the desk.
+type SurfaceGroup struct {
+	units []Unit
+
+	mu     sync.RWMutex
+	order  []int
+	strips []*channelStrip
+}
+
+// NewSurfaceGroup returns a group of units ordered from left to right.
+func NewSurfaceGroup(units ...Unit) *SurfaceGroup {
+	g := &SurfaceGroup{units: units}
+	for i := range units {
+		g.order = append(g.order, i)
+	}
+	g.layout()
+	return g
+}
+
+// Reorder arranges the units from left to right. order lists every unit exactly once by its position in the
+// arguments to NewSurfaceGroup; for example, Reorder(1, 0) puts the second unit left of the first.
+func (g *SurfaceGroup) Reorder(order ...int) error {
+	if len(order) != len(g.units) {
+		return fmt.Errorf("invalid unit order %v: must list all %d units", order, len(g.units))
+	}
+	seen := make([]bool, len(g.units))
+	for _, u := range order {
+		if u < 0 || u >= len(g.units) || seen[u] {
+			return fmt.Errorf("invalid unit order %v: must list each unit from 0 to %d once", order, len(g.units)-1)
+		}
+		seen[u] = true
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.order = append([]int(nil), order...)
+	g.layout()
+	return nil
+}
+
+// layout rebuilds the strip index space from the unit order. The caller must hold g.mu unless g is not yet shared.
+func (g *SurfaceGroup) layout() {
+	g.strips = g.strips[:0]
+	for _, u := range g.order {
+		g.strips = append(g.strips, g.units[u].Strips()...)
+	}
+}
+
+// Len returns the number of strips across all units.
+func (g *SurfaceGroup) Len() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.strips)
+}
+
+// Strip returns strip i, counting from 0 at the left of the leftmost unit. It panics if i is out of range, like
+// indexing a unit's Channels.
+func (g *SurfaceGroup) Strip(i int) *channelStrip {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.strips[i]
+}
+
+// Strips returns every strip from left to right.
+func (g *SurfaceGroup) Strips() []*channelStrip {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return append([]*channelStrip(nil), g.strips...)
+}
+
+// Locate returns the position, in the arguments to NewSurfaceGroup, of the unit that holds strip i along with the
+// strip's index on that unit.
+func (g *SurfaceGroup) Locate(i int) (unit, strip int, err error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if i < 0 || i >= len(g.strips) {
+		return 0, 0, fmt.Errorf("invalid strip %d: must be between 0 and %d", i, len(g.strips)-1)
+	}
+	for _, u := range g.order {
+		n := len(g.units[u].Strips())
+		if i < n {
+			return u, i, nil
+		}
+		i -= n
+	}
+	panic("unreachable")
+}
+
+// Run runs every unit until ctx is cancelled. If any unit fails, the others are stopped and the first error is
+// returned.
+func (g *SurfaceGroup) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(g.units))
+	for _, u := range g.units {
+		go func() {
+			errs <- u.Run(ctx)
+		}()
+	}
+	var first error
+	for range g.units {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+			cancel()
+		}
+	}
+	return first
+}
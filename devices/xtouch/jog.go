@@ -0,0 +1,159 @@
+package xtouch
+
+import (
+	"sync"
+	"time"
+
+	dev "github.com/jdginn/arpad/devices"
+)
+
+// ccJogWheel is the CC the jog wheel sends on channel 0 for each detent it is turned.
+const ccJogWheel uint8 = 60
+
+// JogMode tells callers what a turn of the jog wheel is meant to do.
+type JogMode uint8
+
+const (
+	// Shuttle is the default mode: turns move the playhead by an amount that grows with the wheel's speed.
+	Shuttle JogMode = iota
+	// Scrub is selected with the SCRUB button: turns play audio under the playhead as it moves.
+	Scrub
+)
+
+func (m JogMode) String() string {
+	if m == Scrub {
+		return "scrub"
+	}
+	return "shuttle"
+}
+
+// JogEvent describes one turn of the jog wheel.
+type JogEvent struct {
+	// Delta is the signed number of detents turned, positive clockwise.
+	Delta int
+	// Velocity is a smoothed estimate of how fast the wheel is spinning, in detents per second, positive clockwise.
+	Velocity float64
+	// Mode is the mode the wheel was in when it was turned.
+	Mode JogMode
+}
+
+const (
+	// jogIdle is how long the wheel must be still before the next turn is treated as starting from rest.
+	jogIdle = 250 * time.Millisecond
+	// jogSmoothing is the weight given to the speed of the latest turn when updating the velocity estimate.
+	jogSmoothing = 0.5
+	// jogMinInterval bounds the speed estimated from turns that arrive back to back.
+	jogMinInterval = time.Millisecond
+)
+
+// JogWheel is the large wheel to the right of the faders. It sends relative turns like an Encoder, without an LED
+// ring, and can be switched between Shuttle and Scrub modes with the SCRUB button.
+type JogWheel struct {
+	d   *dev.MidiDevice
+	now func() time.Time
+
+	relative RelativeMode
+
+	mu       sync.Mutex
+	mode     JogMode
+	scrubLED *led
+	last     time.Time
+	velocity float64
+}
+
+func (x *XTouch) NewJogWheel() *JogWheel {
+	return &JogWheel{
+		d:   x.base,
+		now: time.Now,
+	}
+}
+
+// SetRelativeMode selects how turns reported by the wheel are decoded. The wheel defaults to SignMagnitude.
+func (j *JogWheel) SetRelativeMode(m RelativeMode) *JogWheel {
+	j.relative = m
+	return j
+}
+
+// ScrubWith makes each press of button toggle between Shuttle and Scrub modes, lighting its LED in Scrub mode.
+func (j *JogWheel) ScrubWith(button *Button) *JogWheel {
+	j.mu.Lock()
+	j.scrubLED = button.LED
+	j.mu.Unlock()
+	button.On.Bind(func() error {
+		if j.Mode() == Scrub {
+			return j.SetMode(Shuttle)
+		}
+		return j.SetMode(Scrub)
+	})
+	return j
+}
+
+// SetMode switches the wheel to mode m.
+func (j *JogWheel) SetMode(m JogMode) error {
+	j.mu.Lock()
+	j.mode = m
+	scrubLED := j.scrubLED
+	j.mu.Unlock()
+	if scrubLED != nil {
+		return scrubLED.Set(m == Scrub)
+	}
+	return nil
+}
+
+// Mode returns the mode the wheel is in.
+func (j *JogWheel) Mode() JogMode {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.mode
+}
+
+// Bind specifies the callback to run each time the wheel is turned.
+func (j *JogWheel) Bind(callback func(JogEvent) error) func() {
+	return j.d.CC(0, ccJogWheel).Bind(func(v uint8) error {
+		steps := j.relative.Decode(v)
+		if steps == 0 {
+			return nil
+		}
+		return callback(j.turn(steps))
+	})
+}
+
+// BindDelta specifies the callback to run with the signed number of detents each time the wheel is turned,
+// regardless of mode.
+func (j *JogWheel) BindDelta(callback func(int) error) func() {
+	return j.Bind(func(e JogEvent) error {
+		return callback(e.Delta)
+	})
+}
+
+// Accumulate applies each turn of the wheel to a and runs callback with the resulting value whenever it changes.
+// Paired with an Accumulator of high resolution, the wheel makes a fine adjustment control for a value such as a
+// mixer gain.
+func (j *JogWheel) Accumulate(a *Accumulator, callback func(float64) error) func() {
+	return j.BindDelta(func(steps int) error {
+		if v, changed := a.Add(steps); changed {
+			return callback(v)
+		}
+		return nil
+	})
+}
+
+// turn records a turn of steps detents and returns the event describing it.
+func (j *JogWheel) turn(steps int) JogEvent {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := j.now()
+	elapsed := now.Sub(j.last)
+	reversed := (steps > 0) != (j.velocity > 0)
+	if j.last.IsZero() || elapsed >= jogIdle || reversed {
+		// Starting from rest: assume the wheel has been turning at the slowest speed we would still call moving.
+		j.velocity = float64(steps) / jogIdle.Seconds()
+	} else {
+		speed := float64(steps) / max(elapsed, jogMinInterval).Seconds()
+		j.velocity += jogSmoothing * (speed - j.velocity)
+	}
+	j.last = now
+
+	return JogEvent{Delta: steps, Velocity: j.velocity, Mode: j.mode}
+}
@@ -0,0 +1,252 @@
+package xtouch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	dev "github.com/jdginn/arpad/devices"
+)
+
+const (
+	// Meter levels are sent as channel pressure on channel 0 with the channel strip in the high nibble and the
+	// level in the low nibble.
+	meterMaxLevel  uint8 = 8 // X-Touch meters have eight segments
+	meterSetClip   uint8 = 0x0E
+	meterClearClip uint8 = 0x0F
+)
+
+// MeterScale is the level, in dBFS, at which each segment of a meter lights, from the bottom segment up.
+var MeterScale = [meterMaxLevel]float64{-60, -48, -36, -24, -18, -12, -6, 0}
+
+// MeterSegments returns the number of meter segments lit by a level in dBFS.
+func MeterSegments(db float64) uint8 {
+	var n uint8
+	for _, threshold := range MeterScale {
+		if db >= threshold {
+			n++
+		}
+	}
+	return n
+}
+
+// Meter is a channel strip's signal level meter.
+//
+// The X-Touch decays meters on its own, a segment at a time, so a level has to be resent to hold it. Use a
+// MeterEngine to drive meters with consistent ballistics.
+type Meter struct {
+	d *dev.MidiDevice
+
+	channel uint8
+}
+
+func (x *XTouch) NewMeter(channel uint8) *Meter {
+	return &Meter{
+		d:       x.base,
+		channel: channel,
+	}
+}
+
+// Send shows val in [0.0, 1.0] on the meter, lighting segments in proportion to it.
+func (m *Meter) Send(val float64) error {
+	if val < 0 || val > 1.0 {
+		return fmt.Errorf("invalid val %f: val must be between 0 and 1.0", val)
+	}
+	return m.send(uint8(math.Round(val * float64(meterMaxLevel))))
+}
+
+// SendDB shows a level in dBFS on the meter according to MeterScale.
+func (m *Meter) SendDB(db float64) error {
+	return m.send(MeterSegments(db))
+}
+
+// SetClip lights or clears the meter's clip indicator.
+func (m *Meter) SetClip(on bool) error {
+	if on {
+		return m.send(meterSetClip)
+	}
+	return m.send(meterClearClip)
+}
+
+func (m *Meter) send(v uint8) error {
+	return m.d.Aftertouch(0).Set(m.channel<<4 | v)
+}
+
+// meterFloorDB is the level a meter rests at when there is no signal.
+const meterFloorDB = -120
+
+// Ballistics configures how a MeterEngine's meters respond to the levels fed to them.
+type Ballistics struct {
+	// Decay is how fast a meter falls once its peak hold has passed, in dB per second.
+	Decay float64
+	// PeakHold is how long a meter holds a new peak before it starts to fall.
+	PeakHold time.Duration
+	// ClipLevel is the level, in dBFS, at or above which the clip indicator lights.
+	ClipLevel float64
+	// ClipHold is how long the clip indicator stays lit after the last clipping level. Zero latches it until
+	// ResetClips.
+	ClipHold time.Duration
+}
+
+// DefaultBallistics holds peaks for half a second and then falls at 20 dB per second, like a digital peak meter.
+// The clip indicator latches at 0 dBFS.
+var DefaultBallistics = Ballistics{
+	Decay:     20,
+	PeakHold:  500 * time.Millisecond,
+	ClipLevel: 0,
+}
+
+// MeterEngine drives a bank of meters from levels fed to it at any rate, refreshing the surface at a fixed cadence
+// so that meters rise immediately, fall smoothly and never freeze at a stale level.
+//
+//	meters := xtouch.NewMeterEngine(xtouch.DefaultBallistics, x.Channels[0].Meter, x.Channels[1].Meter)
+//	go meters.Run(ctx, 30)
+//	meters.SetDB(0, -12)
+type MeterEngine struct {
+	ballistics Ballistics
+	meters     []*Meter
+	now        func() time.Time
+
+	mu     sync.Mutex
+	states []meterState
+	resend bool // whether the next tick must send every meter
+}
+
+// meterState is what the engine tracks for one meter.
+type meterState struct {
+	peak   float64 // the level the meter was last pushed up to
+	peakAt time.Time
+	clip   bool
+	clipAt time.Time
+
+	sentLevel uint8
+	sentClip  bool
+}
+
+// NewMeterEngine returns an engine driving meters with ballistics b. Meters are addressed by their index in meters.
+func NewMeterEngine(b Ballistics, meters ...*Meter) *MeterEngine {
+	states := make([]meterState, len(meters))
+	for i := range states {
+		states[i].peak = meterFloorDB
+	}
+	return &MeterEngine{
+		ballistics: b,
+		meters:     meters,
+		now:        time.Now,
+		states:     states,
+	}
+}
+
+// SetDB feeds a level in dBFS to meter i. A level above what the meter shows raises it at the next refresh; lower
+// levels are left to the meter's decay.
+func (e *MeterEngine) SetDB(i int, db float64) error {
+	if i < 0 || i >= len(e.states) {
+		return fmt.Errorf("invalid meter %d: must be between 0 and %d", i, len(e.states)-1)
+	}
+	now := e.now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s := &e.states[i]
+	if db >= s.level(now, e.ballistics) {
+		s.peak, s.peakAt = db, now
+	}
+	if db >= e.ballistics.ClipLevel {
+		s.clip, s.clipAt = true, now
+	}
+	return nil
+}
+
+// SetLinear feeds a linear amplitude to meter i, where 1.0 is full scale.
+func (e *MeterEngine) SetLinear(i int, amplitude float64) error {
+	db := float64(meterFloorDB)
+	if amplitude > 0 {
+		db = 20 * math.Log10(amplitude)
+	}
+	return e.SetDB(i, db)
+}
+
+// ResetClips clears every clip indicator at the next refresh.
+func (e *MeterEngine) ResetClips() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i := range e.states {
+		e.states[i].clip = false
+	}
+}
+
+// Refresh makes the next tick resend every meter and clip indicator, e.g. after the surface has been power-cycled.
+func (e *MeterEngine) Refresh() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.resend = true
+	return nil
+}
+
+// level returns the level, in dBFS, the meter shows at now.
+func (s *meterState) level(now time.Time, b Ballistics) float64 {
+	falling := now.Sub(s.peakAt) - b.PeakHold
+	if falling <= 0 {
+		return s.peak
+	}
+	return math.Max(s.peak-b.Decay*falling.Seconds(), meterFloorDB)
+}
+
+// Tick brings every meter up to date. Lit meters are resent on every tick so that the surface's own decay does not
+// take over; dark meters and clip indicators are only sent when they change.
+func (e *MeterEngine) Tick() error {
+	type update struct {
+		level, clip         uint8
+		sendLevel, sendClip bool
+	}
+	now := e.now()
+	updates := make([]update, len(e.states))
+
+	e.mu.Lock()
+	for i := range e.states {
+		s := &e.states[i]
+		if s.clip && e.ballistics.ClipHold > 0 && now.Sub(s.clipAt) >= e.ballistics.ClipHold {
+			s.clip = false
+		}
+		u := &updates[i]
+		u.level = MeterSegments(s.level(now, e.ballistics))
+		u.sendLevel = e.resend || u.level > 0 || u.level != s.sentLevel
+		u.clip, u.sendClip = meterClearClip, e.resend || s.clip != s.sentClip
+		if s.clip {
+			u.clip = meterSetClip
+		}
+		s.sentLevel, s.sentClip = u.level, s.clip
+	}
+	e.resend = false
+	e.mu.Unlock()
+
+	var errs error
+	for i, u := range updates {
+		if u.sendLevel {
+			errs = errors.Join(errs, e.meters[i].send(u.level))
+		}
+		if u.sendClip {
+			errs = errors.Join(errs, e.meters[i].send(u.clip))
+		}
+	}
+	return errs
+}
+
+// Run calls Tick rate times per second until ctx is cancelled. Errors from individual ticks are logged.
+func (e *MeterEngine) Run(ctx context.Context, rate float64) {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.Tick(); err != nil {
+				log.Error("failed to refresh meters", slog.Any("err", err))
+			}
+		}
+	}
+}
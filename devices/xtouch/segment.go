@@ -0,0 +1,251 @@
+package xtouch
+
+import (
+	"fmt"
+	"sync"
+	"unicode"
+
+	dev "github.com/jdginn/arpad/devices"
+)
+
+const (
+	ccSegment    uint8 = 96  // CC 96-107: segments of digits 1-12 with the dot off
+	ccSegmentDot uint8 = 112 // CC 112-123: segments of digits 1-12 with the dot lit
+
+	// NumAssignmentDigits is the width of the two-digit assignment display left of the timecode.
+	NumAssignmentDigits = 2
+	// NumTimecodeDigits is the width of the timecode display, grouped 3-2-2-3.
+	NumTimecodeDigits = 10
+
+	numDigits = NumAssignmentDigits + NumTimecodeDigits
+)
+
+// Segment bits. Digits are drawn as
+//
+//	 aaa
+//	f   b
+//	 ggg
+//	e   c
+//	 ddd
+const (
+	segA uint8 = 1 << iota
+	segB
+	segC
+	segD
+	segE
+	segF
+	segG
+)
+
+// segmentFont approximates characters on a 7-segment digit. Letters missing here are drawn in their other case if
+// that has a glyph.
+var segmentFont = map[rune]uint8{
+	' ':  0,
+	'0':  segA | segB | segC | segD | segE | segF,
+	'1':  segB | segC,
+	'2':  segA | segB | segD | segE | segG,
+	'3':  segA | segB | segC | segD | segG,
+	'4':  segB | segC | segF | segG,
+	'5':  segA | segC | segD | segF | segG,
+	'6':  segA | segC | segD | segE | segF | segG,
+	'7':  segA | segB | segC,
+	'8':  segA | segB | segC | segD | segE | segF | segG,
+	'9':  segA | segB | segC | segD | segF | segG,
+	'A':  segA | segB | segC | segE | segF | segG,
+	'b':  segC | segD | segE | segF | segG,
+	'C':  segA | segD | segE | segF,
+	'c':  segD | segE | segG,
+	'd':  segB | segC | segD | segE | segG,
+	'E':  segA | segD | segE | segF | segG,
+	'F':  segA | segE | segF | segG,
+	'G':  segA | segC | segD | segE | segF,
+	'H':  segB | segC | segE | segF | segG,
+	'h':  segC | segE | segF | segG,
+	'I':  segE | segF,
+	'i':  segE,
+	'J':  segB | segC | segD | segE,
+	'L':  segD | segE | segF,
+	'n':  segC | segE | segG,
+	'O':  segA | segB | segC | segD | segE | segF,
+	'o':  segC | segD | segE | segG,
+	'P':  segA | segB | segE | segF | segG,
+	'q':  segA | segB | segC | segF | segG,
+	'r':  segE | segG,
+	'S':  segA | segC | segD | segF | segG,
+	't':  segD | segE | segF | segG,
+	'U':  segB | segC | segD | segE | segF,
+	'u':  segC | segD | segE,
+	'y':  segB | segC | segD | segF | segG,
+	'-':  segG,
+	'_':  segD,
+	'=':  segD | segG,
+	'"':  segB | segF,
+	'\'': segF,
+}
+
+// Segments returns the segment bits that draw r, or a blank digit if r has no 7-segment approximation.
+func Segments(r rune) uint8 {
+	if s, ok := segmentFont[r]; ok {
+		return s
+	}
+	if s, ok := segmentFont[unicode.ToUpper(r)]; ok {
+		return s
+	}
+	return segmentFont[unicode.ToLower(r)]
+}
+
+// TimecodeDisplay drives the row of 7-segment digits above the jog wheel: the two-digit assignment display on the
+// left and the ten-digit timecode display grouped hours/bars (3), minutes/beats (2), seconds/sub-divisions (2) and
+// frames/ticks (3). Each digit has a dot at its lower right.
+//
+// The display keeps what is shown and only sends digits that change.
+type TimecodeDisplay struct {
+	d *dev.MidiDevice
+
+	mu       sync.Mutex
+	segments [numDigits]uint8
+	dots     [numDigits]bool
+
+	// What each digit last showed, so that unchanged digits are not resent.
+	sentSegments [numDigits]uint8
+	sentDots     [numDigits]bool
+	sentOK       [numDigits]bool
+}
+
+func (x *XTouch) NewTimecodeDisplay() *TimecodeDisplay {
+	t := &TimecodeDisplay{d: x.base}
+	x.track(t)
+	return t
+}
+
+// SetAssignment shows s, right-aligned, on the assignment display. A '.' lights the dot of the digit before it.
+func (t *TimecodeDisplay) SetAssignment(s string) error {
+	return t.setText(0, NumAssignmentDigits, s)
+}
+
+// SetTimecode shows s, right-aligned, on the timecode display. A '.' lights the dot of the digit before it, so
+// "12.34" uses four digits.
+func (t *TimecodeDisplay) SetTimecode(s string) error {
+	return t.setText(NumAssignmentDigits, NumTimecodeDigits, s)
+}
+
+// SetSMPTE shows an SMPTE time on the timecode display, with dots separating the fields.
+func (t *TimecodeDisplay) SetSMPTE(hours, minutes, seconds, frames int) error {
+	return t.SetTimecode(fmt.Sprintf("%3d.%02d.%02d.%03d", hours%1000, minutes%100, seconds%100, frames%1000))
+}
+
+// SetBarsBeats shows a musical position on the timecode display, with dots separating the fields.
+func (t *TimecodeDisplay) SetBarsBeats(bars, beats, subdivisions, ticks int) error {
+	return t.SetTimecode(fmt.Sprintf("%3d.%2d.%02d.%03d", bars%1000, beats%100, subdivisions%100, ticks%1000))
+}
+
+// SetDigit sets the segments of one digit, counting from 0 at the left of the assignment display, leaving its dot
+// as it is.
+func (t *TimecodeDisplay) SetDigit(digit int, segments uint8) error {
+	if digit < 0 || digit >= numDigits {
+		return fmt.Errorf("invalid digit %d: must be between 0 and %d", digit, numDigits-1)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.segments[digit] = segments & 0x7F
+	return t.flush()
+}
+
+// SetDot lights or clears the dot of one digit, counting from 0 at the left of the assignment display.
+func (t *TimecodeDisplay) SetDot(digit int, on bool) error {
+	if digit < 0 || digit >= numDigits {
+		return fmt.Errorf("invalid digit %d: must be between 0 and %d", digit, numDigits-1)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dots[digit] = on
+	return t.flush()
+}
+
+// Clear blanks every digit and dot.
+func (t *TimecodeDisplay) Clear() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.segments = [numDigits]uint8{}
+	t.dots = [numDigits]bool{}
+	return t.flush()
+}
+
+// Refresh resends every digit, e.g. after the surface has been power-cycled.
+func (t *TimecodeDisplay) Refresh() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sentOK = [numDigits]bool{}
+	return t.flush()
+}
+
+func (t *TimecodeDisplay) refresh() error { return t.Refresh() }
+
+// timecodeState is what a TimecodeDisplay shows.
+type timecodeState struct {
+	segments [numDigits]uint8
+	dots     [numDigits]bool
+}
+
+func (t *TimecodeDisplay) save() any {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return timecodeState{t.segments, t.dots}
+}
+
+func (t *TimecodeDisplay) restore(state any) error {
+	st := state.(timecodeState)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.segments, t.dots = st.segments, st.dots
+	return t.flush()
+}
+
+// setText renders s right-aligned into the width digits starting at first.
+func (t *TimecodeDisplay) setText(first, width int, s string) error {
+	var segments []uint8
+	var dots []bool
+	for _, r := range s {
+		if r == '.' && len(segments) > 0 && !dots[len(dots)-1] {
+			dots[len(dots)-1] = true
+			continue
+		}
+		segments = append(segments, Segments(r))
+		dots = append(dots, r == '.')
+		if r == '.' {
+			segments[len(segments)-1] = 0
+		}
+	}
+	if len(segments) > width {
+		return fmt.Errorf("%q needs %d digits but the display has %d", s, len(segments), width)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	pad := width - len(segments)
+	for i := 0; i < width; i++ {
+		t.segments[first+i], t.dots[first+i] = 0, false
+		if i >= pad {
+			t.segments[first+i], t.dots[first+i] = segments[i-pad], dots[i-pad]
+		}
+	}
+	return t.flush()
+}
+
+// flush sends every digit that differs from what the surface shows. The caller must hold t.mu.
+func (t *TimecodeDisplay) flush() error {
+	for i := 0; i < numDigits; i++ {
+		if t.sentOK[i] && t.sentSegments[i] == t.segments[i] && t.sentDots[i] == t.dots[i] {
+			continue
+		}
+		cc := ccSegment + uint8(i)
+		if t.dots[i] {
+			cc = ccSegmentDot + uint8(i)
+		}
+		if err := t.d.CC(0, cc).Set(t.segments[i]); err != nil {
+			return fmt.Errorf("failed to set timecode digit %d: %w", i, err)
+		}
+		t.sentSegments[i], t.sentDots[i], t.sentOK[i] = t.segments[i], t.dots[i], true
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package xtouch
+
+import (
+	"errors"
+	"fmt"
+)
+
+// output is a part of the surface that arpad draws: an LED, a fader, an encoder ring, a scribble strip or a display.
+// Each output keeps the state it is meant to show, which lets it skip sends that would not change anything and put
+// itself back after the surface has been power-cycled.
+type output interface {
+	// refresh resends the intended state whether or not the surface is believed to show it already.
+	refresh() error
+	// save returns a copy of the intended state, or nil if there is nothing to restore.
+	save() any
+	// restore makes state, as returned by save, the intended state and sends it if the surface shows otherwise.
+	restore(state any) error
+}
+
+// refreshOnly is an output, such as the meters, whose state is too short-lived to be worth restoring.
+type refreshOnly func() error
+
+func (r refreshOnly) refresh() error      { return r() }
+func (r refreshOnly) save() any           { return nil }
+func (r refreshOnly) restore(_ any) error { return nil }
+
+// track registers an output so that it takes part in Refresh, Snapshot and Restore.
+func (x *XTouch) track(o output) {
+	x.outputsMu.Lock()
+	defer x.outputsMu.Unlock()
+	x.outputs = append(x.outputs, o)
+}
+
+// tracked returns the registered outputs in the order they were created.
+func (x *XTouch) tracked() []output {
+	x.outputsMu.Lock()
+	defer x.outputsMu.Unlock()
+	return append([]output(nil), x.outputs...)
+}
+
+// Refresh resends everything the surface should be showing: fader positions, LEDs, encoder rings, scribble strips,
+// the timecode display and meters. It runs automatically whenever the surface comes online.
+func (x *XTouch) Refresh() error {
+	var errs error
+	for _, o := range x.tracked() {
+		errs = errors.Join(errs, o.refresh())
+	}
+	return errs
+}
+
+// Snapshot is a copy of everything arpad has drawn on a surface, taken with XTouch.Snapshot. Restoring it puts the
+// surface back as it was, so a mode switch can swap whole pages of LEDs, rings, faders and scribble strips at once.
+// Meters are live and are not part of a snapshot.
+type Snapshot struct {
+	x      *XTouch
+	states []any
+}
+
+// Snapshot returns a copy of what every output on the surface is meant to show.
+func (x *XTouch) Snapshot() *Snapshot {
+	outputs := x.tracked()
+	s := &Snapshot{x: x, states: make([]any, len(outputs))}
+	for i, o := range outputs {
+		s.states[i] = o.save()
+	}
+	return s
+}
+
+// Restore makes the surface show what it showed when s was taken. Only outputs that differ from what the surface is
+// believed to show are sent. An output arpad has never drawn always counts as different, so the first Restore that is
+// not preceded by a Refresh resends every button LED and encoder ring. Faders that had never been moved when s was
+// taken stay where they are.
+func (x *XTouch) Restore(s *Snapshot) error {
+	if s == nil || s.x != x {
+		return fmt.Errorf("cannot restore a snapshot of another surface")
+	}
+	var errs error
+	for i, o := range x.tracked()[:len(s.states)] {
+		if s.states[i] != nil {
+			errs = errors.Join(errs, o.restore(s.states[i]))
+		}
+	}
+	return errs
+}
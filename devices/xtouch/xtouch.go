@@ -1,37 +1,75 @@
 package xtouch
 
 import (
-	"fmt"
-	"math"
+	"bytes"
+	"context"
+	"log/slog"
 	"strings"
 	"sync"
 	"time"
 
 	dev "github.com/jdginn/arpad/devices"
+	"github.com/jdginn/arpad/logging"
 
 	midi "gitlab.com/gomidi/midi/v2"
 )
 
+var log *slog.Logger
+
+func init() {
+	log = logging.Get(logging.APP)
+}
+
+// Device IDs the surfaces use in Mackie Control SysEx messages.
 const (
-	// Handshake message sent by X-Touch every 2 seconds
-	handshakePingMessage = "\x00\x00\x66\x14\x00"
-	// Expected response message (should be received every 6-8 seconds)
-	// handshakeResponseMessage = "\x00\x20\x32\x58\x54\x00"
-	handshakeResponseMessage = "\x00\x00\x66\x14\x01\x30\x31\x35\x36\x34\x30\x33\x35\x38\x43\x41"
+	deviceIDXTouch   byte = 0x14
+	deviceIDExtender byte = 0x15
+)
 
+const (
 	// Timing constants
 	pingInterval    = 1 * time.Second
 	responseTimeout = 4 * time.Second
 )
 
+// handshakePingMessage is sent to the surface every pingInterval to keep it in Mackie Control mode.
+func handshakePingMessage(deviceID byte) []byte {
+	return []byte{0x00, 0x00, 0x66, deviceID, 0x00}
+}
+
+// handshakeResponsePrefix begins the surface's reply to a ping, which goes on to carry its serial number.
+func handshakeResponsePrefix(deviceID byte) []byte {
+	return []byte{0x00, 0x00, 0x66, deviceID, 0x01}
+}
+
+// noteFaderTouch is the note a fader sends when touched on its channel; notes 104-112 cover channels 0-8.
+const noteFaderTouch uint8 = 104
+
 // Fader represents a motorized fader on an xtouch controller.
 //
 // Faders send MIDI PitchBend data on their specified channel.
 // Faders can be remotely moved at will using SetFader*.
+//
+// Faders are touch-sensitive. While a finger is on the fader, Set calls are held back so the motor does not fight
+// the engineer's hand, and the most recent one is applied when the fader is released.
 type Fader struct {
 	d *dev.MidiDevice
 
 	ChannelNo uint8
+
+	// Touch fires when a finger lands on the fader and Release when it lifts.
+	Touch   *buttonOn
+	Release *buttonOff
+
+	mu      sync.Mutex
+	touched bool
+	pending bool
+	known   bool // whether pos has ever been set
+	pos     uint16
+
+	// Where the fader was last sent or moved by hand, so that a move to where it already is can be skipped.
+	shown   uint16
+	shownOK bool
 }
 
 func (f *Fader) Bind(callback func(uint16) error) func() {
@@ -44,8 +82,54 @@ func (f *Fader) Bind(callback func(uint16) error) func() {
 		})
 }
 
+// Set moves the motorized fader. If the fader is being touched, the move is held until it is released. Moves to where
+// the fader already is are skipped.
 func (f *Fader) Set(val uint16) error {
-	return f.d.PitchBend(uint8(f.ChannelNo)).Set(val)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pos, f.known = val, true
+	if f.touched {
+		f.pending = true
+		return nil
+	}
+	if f.shownOK && f.shown == val {
+		return nil
+	}
+	return f.send()
+}
+
+// send moves the fader to pos. The caller must hold f.mu.
+func (f *Fader) send() error {
+	err := f.d.PitchBend(uint8(f.ChannelNo)).Set(f.pos)
+	f.shown, f.shownOK = f.pos, err == nil
+	return err
+}
+
+// moved records where the engineer's hand has put the fader.
+func (f *Fader) moved(v uint16) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.shown, f.shownOK = v, true
+	return nil
+}
+
+// Touched reports whether a finger is currently on the fader.
+func (f *Fader) Touched() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.touched
+}
+
+func (f *Fader) setTouched(touched bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.touched = touched
+	send := !touched && f.pending
+	f.pending = false
+	if send {
+		return f.send()
+	}
+	return nil
 }
 
 type ScribbleColor int
@@ -70,140 +154,182 @@ const (
 
 type SysExHeader []byte
 
-var HeaderScribble SysExHeader = []byte{0x00, 0x00, 0x66, 0x58}
+var (
+	HeaderScribble         SysExHeader = []byte{0x00, 0x00, 0x66, 0x58}
+	HeaderScribbleExtender SysExHeader = []byte{0x00, 0x00, 0x66, 0x59}
+)
+
+// Bits of the scribble strip color byte that invert one line, showing dark text on a lit background.
+const (
+	scribbleInvertTop    = 0x10
+	scribbleInvertBottom = 0x20
+)
+
+// scribbleWidth is the number of characters on each line of a scribble strip.
+const scribbleWidth = 7
 
+// Scribble is the small two-line LCD above a channel strip. Its backlight takes one of eight colors and each line can
+// be inverted.
+//
+// Changes are staged with the Change methods and sent with Set, which skips the SysEx entirely when the strip
+// already shows the same thing.
 type Scribble struct {
-	d *dev.MidiDevice
+	d      *dev.MidiDevice
+	header SysExHeader
+
+	channel uint8
 
-	channel       uint8
+	// The staged changes, and what was last sent. mu guards them so a Restore can run alongside the app's Sets.
+	mu            sync.Mutex
 	color         ScribbleColor
+	invertTop     bool
+	invertBottom  bool
+	topMessage    string
+	bottomMessage string
+	shown         scribbleState // what was last Set
+	sent          []byte        // what the strip is believed to show
+}
+
+// scribbleState is what a scribble strip shows.
+type scribbleState struct {
+	color         ScribbleColor
+	invertTop     bool
+	invertBottom  bool
 	topMessage    string
 	bottomMessage string
 }
 
 func (s *Scribble) ChangeColor(c ScribbleColor) *Scribble {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.color = c
 	return s
 }
 
-func normalizeTo7CharsNullPad(m string) string {
-	vowels := "aeiouAEIOU"
-	runes := []rune(m)
+// ChangeInversion sets which lines are shown inverted, independently of the color.
+func (s *Scribble) ChangeInversion(top, bottom bool) *Scribble {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.invertTop, s.invertBottom = top, bottom
+	return s
+}
 
-	// Step 1: If longer than 7, try to remove vowels left to right
-	for len(runes) > 7 {
-		found := false
-		for i, r := range runes {
-			if strings.ContainsRune(vowels, r) {
-				runes = append(runes[:i], runes[i+1:]...)
-				found = true
-				break
-			}
-		}
-		// If no vowels left to remove, break
-		if !found {
-			break
-		}
-	}
+func (s *Scribble) ChangeTopMessage(m string) *Scribble {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.topMessage = m
+	return s
+}
 
-	// Step 2: If still too long, truncate
-	if len(runes) > 7 {
-		runes = runes[:7]
-	}
+func (s *Scribble) ChangeBottomMessage(m string) *Scribble {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bottomMessage = m
+	return s
+}
 
-	// Step 3: If less than 7, pad with 0x00 at the front
-	padLen := 7 - len(runes)
-	if padLen > 0 {
-		pad := make([]rune, padLen)
-		for i := range pad {
-			pad[i] = '\x00'
+// scribbleLine abbreviates m to fit a line of the strip and replaces characters the strip cannot show.
+func scribbleLine(m string) []byte {
+	line := make([]byte, 0, scribbleWidth)
+	for _, r := range Abbreviate(m, scribbleWidth) {
+		if r < 0x20 || r > 0x7E {
+			r = '?'
 		}
-		runes = append(runes, pad...)
+		line = append(line, byte(r))
 	}
+	return line
+}
 
-	return string(runes)
+// normalizeTo7CharsNullPad fits m to a line, left-aligned and padded with nulls.
+func normalizeTo7CharsNullPad(m string) []byte {
+	line := scribbleLine(m)
+	return append(line, make([]byte, scribbleWidth-len(line))...)
 }
 
-func (s *Scribble) ChangeTopMessage(m string) *Scribble {
-	s.topMessage = m
-	return s
+// normalizeTo7CharsPrependSpace fits m to a line, right-aligned and padded with spaces.
+func normalizeTo7CharsPrependSpace(m string) []byte {
+	line := scribbleLine(m)
+	return append([]byte(strings.Repeat(" ", scribbleWidth-len(line))), line...)
 }
 
-func normalizeTo7CharsPrependSpace(m string) string {
-	vowels := "aeiouAEIOU"
+// Set sends the staged color and messages to the strip, unless it already shows them.
+func (s *Scribble) Set() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.set()
+}
 
-	// Step 1: If already 7, return as is
-	if len(m) == 7 {
-		return m
+// set is Set for callers that hold s.mu.
+func (s *Scribble) set() error {
+	s.shown = scribbleState{s.color, s.invertTop, s.invertBottom, s.topMessage, s.bottomMessage}
+	b := s.sysex()
+	if bytes.Equal(b, s.sent) {
+		return nil
 	}
+	return s.send(b)
+}
 
-	// Step 2: If less than 7, pad with spaces at the front
-	if len(m) < 7 {
-		return strings.Repeat(" ", 7-len(m)) + m
+// sysex returns the message that makes the strip show what was last Set. The caller must hold s.mu.
+func (s *Scribble) sysex() []byte {
+	color := byte(s.shown.color)
+	if s.shown.invertTop {
+		color |= scribbleInvertTop
 	}
-
-	// Step 3: If longer than 7, try to remove vowels (from left to right)
-	// Convert to []rune for Unicode safety
-	runes := []rune(m)
-	// Remove vowels left to right until length <= 7 or no vowels left
-	for len(runes) > 7 {
-		found := false
-		for i, r := range runes {
-			if strings.ContainsRune(vowels, r) {
-				runes = append(runes[:i], runes[i+1:]...)
-				found = true
-				break
-			}
-		}
-		// If no vowels left to remove, break the loop
-		if !found {
-			break
-		}
+	if s.shown.invertBottom {
+		color |= scribbleInvertBottom
 	}
-	// Step 4: If still too long, truncate
-	if len(runes) > 7 {
-		runes = runes[:7]
-	}
-
-	return string(runes)
+	b := append(append([]byte{}, s.header...), 0x20+s.channel, color)
+	b = append(b, normalizeTo7CharsNullPad(s.shown.topMessage)...)
+	return append(b, normalizeTo7CharsPrependSpace(s.shown.bottomMessage)...)
 }
 
-func (s *Scribble) ChangeBottomMessage(m string) *Scribble {
-	s.bottomMessage = m
-	return s
+// send sends b to the strip. The caller must hold s.mu.
+func (s *Scribble) send(b []byte) error {
+	s.sent = nil
+	if err := s.d.SysEx.Set(midi.SysEx(b)); err != nil {
+		return err
+	}
+	s.sent = b
+	return nil
 }
 
-func (s *Scribble) Set() error {
-	b := append([]byte{0x00, 0x20, 0x32, 0x14, 0x4c}, byte(s.channel))
-	// b = append(b, byte(s.color))
-	b = append(b, 0x60)
-	b = append(b, []byte(normalizeTo7CharsNullPad(s.topMessage))...)
-	b = append(b, []byte(normalizeTo7CharsPrependSpace(s.bottomMessage))...)
-	return s.d.SysEx.Set(midi.SysEx(b))
+// refresh resends what the strip was last Set to show, blanking it if it has never been Set.
+func (s *Scribble) refresh() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.send(s.sysex())
 }
 
-type Meter struct {
-	d *dev.MidiDevice
-
-	channel uint8
+func (s *Scribble) save() any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shown
 }
 
-func (m *Meter) Send(val float64) error {
-	if val > 1.0 {
-		return fmt.Errorf("invalid val %f: val must be between 0 and 1.0", val)
-	}
-	offset := m.channel*16 + uint8(math.Round(8*val))
-	return m.d.Aftertouch(0).Set(offset) // TODO: check this
+// restore stages state, replacing any unsent changes, and sets the strip to it.
+func (s *Scribble) restore(state any) error {
+	st := state.(scribbleState)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.color, s.invertTop, s.invertBottom, s.topMessage, s.bottomMessage =
+		st.color, st.invertTop, st.invertBottom, st.topMessage, st.bottomMessage
+	return s.set()
 }
 
 type XTouch struct {
-	base *dev.MidiDevice
+	base     *dev.MidiDevice
+	deviceID byte
 
 	// Handshake management
 	handshakeActive   bool
 	lastResponse      time.Time
 	handshakeMutex    sync.RWMutex
 	handshakeStopChan chan struct{}
+
+	conn connection
+
+	outputsMu sync.Mutex
+	outputs   []output
 }
 
 // startHandshake begins the handshake protocol
@@ -218,8 +344,12 @@ func (x *XTouch) startHandshake() error {
 	x.handshakeStopChan = make(chan struct{})
 	x.handshakeActive = true
 	x.lastResponse = time.Now()
+	x.setConnection(Connecting)
 
-	// Start sending ping messages
+	// Start sending ping messages. The surface may disappear and come back (e.g. when it is power-cycled), so a
+	// missing response marks the connection lost but never ends the handshake; pings pause while the MIDI device is
+	// waiting to reconnect and resume once it has.
+	stop := x.handshakeStopChan
 	go func() {
 		ticker := time.NewTicker(pingInterval)
 		defer ticker.Stop()
@@ -227,35 +357,50 @@ func (x *XTouch) startHandshake() error {
 		for {
 			select {
 			case <-ticker.C:
-				if err := x.base.SysEx.SetSilent(midi.SysEx([]byte(handshakePingMessage))); err != nil {
-					fmt.Printf("Error sending handshake ping: %v\n", err)
+				if !x.checkLiveness() {
+					continue
 				}
-
-				// Check for response timeout
-				x.handshakeMutex.RLock()
-				if time.Since(x.lastResponse) > responseTimeout {
-					fmt.Println("Warning: No handshake response received within timeout period; giving up")
-					return
+				if err := x.base.SysEx.SetSilent(midi.SysEx(handshakePingMessage(x.deviceID))); err != nil {
+					log.Warn("failed to send X-Touch handshake ping", slog.Any("err", err))
 				}
-				x.handshakeMutex.RUnlock()
 
-			case <-x.handshakeStopChan:
+			case <-stop:
 				return
 			}
 		}
 	}()
 
 	// Set up handler for response messages
-	x.base.SysEx.Match([]byte(handshakeResponseMessage)).Bind(func(msg []byte) error {
+	x.base.SysEx.Match(handshakeResponsePrefix(x.deviceID)).Bind(func(msg []byte) error {
 		x.handshakeMutex.Lock()
 		x.lastResponse = time.Now()
 		x.handshakeMutex.Unlock()
+		x.setConnection(Online)
 		return nil
 	})
 
 	return nil
 }
 
+// checkLiveness marks an online surface as lost if its MIDI ports have gone away or it has stopped replying to
+// pings, and reports whether it is worth pinging.
+func (x *XTouch) checkLiveness() bool {
+	if !x.base.IsConnected() {
+		if x.Connection() == Online {
+			x.setConnection(Lost)
+		}
+		return false
+	}
+	x.handshakeMutex.RLock()
+	silent := time.Since(x.lastResponse) > responseTimeout
+	x.handshakeMutex.RUnlock()
+	if silent && x.Connection() == Online {
+		log.Warn("no X-Touch handshake response received within timeout period; still pinging")
+		x.setConnection(Lost)
+	}
+	return true
+}
+
 // stopHandshake stops the handshake protocol
 func (x *XTouch) stopHandshake() {
 	x.handshakeMutex.Lock()
@@ -269,32 +414,65 @@ func (x *XTouch) stopHandshake() {
 	x.handshakeActive = false
 }
 
-func (x *XTouch) Run() {
+// Run starts the surface and blocks until ctx is cancelled or the underlying MIDI device fails to start.
+func (x *XTouch) Run(ctx context.Context) error {
 	if err := x.startHandshake(); err != nil {
-		fmt.Printf("Failed to start handshake: %v\n", err)
+		log.Error("failed to start X-Touch handshake", slog.Any("err", err))
 	}
 	defer x.stopHandshake()
-	x.base.Run()
+	return x.base.Run(ctx)
 }
 
 // NewFader returns a new fader on the given channel.
 //
 // NewFader accepts an optional, variadic list of callbacks to run when the fader is moved.
 func (x *XTouch) NewFader(channelNo uint8) *Fader {
-	return &Fader{
+	touch := x.newButton(0, noteFaderTouch+channelNo)
+	f := &Fader{
 		d:         x.base,
 		ChannelNo: channelNo,
+		Touch:     touch.On,
+		Release:   touch.Off,
+	}
+	f.Touch.Bind(func() error { return f.setTouched(true) })
+	f.Release.Bind(func() error { return f.setTouched(false) })
+	f.d.PitchBend(channelNo).Bind(f.moved)
+	x.track(f)
+	return f
+}
+
+// refresh moves the fader back to its last position, e.g. after the surface has been power-cycled.
+func (f *Fader) refresh() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.known || f.touched {
+		return nil
 	}
+	return f.send()
+}
+
+func (f *Fader) save() any {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.known {
+		return nil
+	}
+	return f.pos
+}
+
+func (f *Fader) restore(state any) error {
+	return f.Set(state.(uint16))
 }
 
 func (x *XTouch) NewEncoder(channelNo uint8, id uint8) *Encoder {
 	// id should be 0-7
-	// encoderCC := 16 + (id % 8) // Maps to CC 16-23
+	encoderCC := 16 + (id % 8) // Maps to CC 16-23
 	ledLowCC := 48 + (id % 8)  // Maps to CC 48-55
 	ledHighCC := 56 + (id % 8) // Maps to CC 56-63
 	enc := &Encoder{
 		d:           x.base,
 		channel:     channelNo,
+		encoderCC:   encoderCC,
 		ledRingLow:  ledLowCC,
 		ledRingHigh: ledHighCC,
 	}
@@ -303,24 +481,23 @@ func (x *XTouch) NewEncoder(channelNo uint8, id uint8) *Encoder {
 		AllSegments:      ringSetAllSegments{enc},
 		ClearAllSegments: ringClearAllSegments{enc},
 	}
+	x.track(&enc.Ring)
 	return enc
 }
 
 func (x *XTouch) NewScribble(channel uint8) *Scribble {
-	return &Scribble{
-		x.base,
-		channel,
-		Off,
-		"", // topMessage
-		"", // bottomMessage
+	header := HeaderScribble
+	if x.deviceID == deviceIDExtender {
+		header = HeaderScribbleExtender
 	}
-}
-
-func (x *XTouch) NewMeter(channel uint8) *Meter {
-	return &Meter{
-		x.base,
-		channel,
+	s := &Scribble{
+		d:       x.base,
+		header:  header,
+		channel: channel,
+		color:   Off,
 	}
+	x.track(s)
+	return s
 }
 
 // channelStrip is a convenience struct that organizes all the components that are replicated
@@ -336,8 +513,6 @@ type channelStrip struct {
 	Select        *Button
 	Meter         *Meter
 	Fader         *Fader
-	// TODO: 7Seg
-	// TODO: JogWheel
 }
 
 // NewChannelStrip returns a new channelStrip corresponding to the given index into a
@@ -345,7 +520,7 @@ type channelStrip struct {
 func (x *XTouch) NewChannelStrip(id uint8) *channelStrip {
 	return &channelStrip{
 		Encoder:       x.NewEncoder(0, id+32),
-		EncoderButton: x.NewButton(0, id+16),
+		EncoderButton: x.NewButton(0, id+32),
 		Scribble:      x.NewScribble(id),
 		Rec:           x.NewButton(0, id),
 		Solo:          x.NewButton(0, id+8),
@@ -560,6 +735,11 @@ type XTouchDefault struct {
 	Transport     *Transport
 	Page          *Page
 	Navigation    *Navigation
+	Timecode      *TimecodeDisplay
+	JogWheel      *JogWheel
+
+	// Meters drives the channel strips' meters. Start it with Meters.Run.
+	Meters *MeterEngine
 }
 
 // New returns a properly initialized XTouchDefault struct.
@@ -567,13 +747,18 @@ func New(d *dev.MidiDevice) *XTouchDefault {
 	x := &XTouchDefault{
 		XTouch: &XTouch{
 			base:            d,
+			deviceID:        deviceIDXTouch,
 			handshakeActive: false,
 			lastResponse:    time.Time{},
 		},
 	}
+	meters := make([]*Meter, 0, 8)
 	for i := 0; i < 8; i++ {
 		x.Channels = append(x.Channels, x.NewChannelStrip(uint8(i)))
+		meters = append(meters, x.Channels[i].Meter)
 	}
+	x.Meters = NewMeterEngine(DefaultBallistics, meters...)
+	x.track(refreshOnly(x.Meters.Refresh))
 	x.EncoderAssign = x.NewEncoderAssign()
 	x.View = x.NewView()
 	x.Function = x.NewFunction()
@@ -583,28 +768,49 @@ func New(d *dev.MidiDevice) *XTouchDefault {
 	x.Transport = x.NewTransport()
 	x.Page = x.NewPage()
 	x.Navigation = x.NewNavigation()
+	x.Timecode = x.NewTimecodeDisplay()
+	x.JogWheel = x.NewJogWheel().ScrubWith(x.Navigation.SCRUB)
 
 	return x
 }
 
-// XTouchExtender represents a Behringer XTouchExtender DAW control surface.
+// Strips returns the surface's channel strips from left to right.
+func (x *XTouchDefault) Strips() []*channelStrip {
+	return x.Channels
+}
+
+// XTouchExtender represents a Behringer XTouchExtender DAW control surface: eight channel strips without the
+// X-Touch's global sections. Join it to an X-Touch with a SurfaceGroup.
 type XTouchExtender struct {
 	*XTouch
 
 	Channels []*channelStrip
+
+	// Meters drives the channel strips' meters. Start it with Meters.Run.
+	Meters *MeterEngine
 }
 
 func NewExtender(d *dev.MidiDevice) *XTouchExtender {
 	x := &XTouchExtender{
 		XTouch: &XTouch{
 			base:            d,
+			deviceID:        deviceIDExtender,
 			handshakeActive: false,
 			lastResponse:    time.Time{},
 		},
 	}
+	meters := make([]*Meter, 0, 8)
 	for i := 0; i < 8; i++ {
-		x.Channels[i] = x.NewChannelStrip(uint8(i))
+		x.Channels = append(x.Channels, x.NewChannelStrip(uint8(i)))
+		meters = append(meters, x.Channels[i].Meter)
 	}
+	x.Meters = NewMeterEngine(DefaultBallistics, meters...)
+	x.track(refreshOnly(x.Meters.Refresh))
 
 	return x
 }
+
+// Strips returns the surface's channel strips from left to right.
+func (x *XTouchExtender) Strips() []*channelStrip {
+	return x.Channels
+}
@@ -1,10 +1,13 @@
 package xtouch
 
 import (
+	"bytes"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	midi "gitlab.com/gomidi/midi/v2"
 
 	dev "github.com/jdginn/arpad/devices"
 	devtest "github.com/jdginn/arpad/devices/devicestesting"
@@ -25,3 +28,472 @@ func TestScribbleString(t *testing.T) {
 	xtouch.Channels[3].Scribble.ChangeColor(Green).ChangeTopMessage("Foo").ChangeBottomMessage("").Set()
 	assert.Equal([]byte{0xf0, 0x00, 0x00, 0x66, 0x58, 0x23, 0x02, 0x46, 0x6f, 0x6f, 0x00, 0x00, 0x00, 0x00, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x020, 0xf7}, midiOut.GetSentMessages()[1].Bytes())
 }
+
+func TestRelativeModes(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(1, SignMagnitude.Decode(1))
+	assert.Equal(-1, SignMagnitude.Decode(65))
+	assert.Equal(5, SignMagnitude.Decode(5), "fast turns report several steps")
+	assert.Equal(-7, SignMagnitude.Decode(71))
+
+	assert.Equal(1, TwosComplement.Decode(1))
+	assert.Equal(-1, TwosComplement.Decode(127))
+	assert.Equal(-64, TwosComplement.Decode(64))
+
+	assert.Equal(1, BinaryOffset.Decode(65))
+	assert.Equal(-1, BinaryOffset.Decode(63))
+	assert.Equal(0, BinaryOffset.Decode(64))
+}
+
+func TestEncoder(t *testing.T) {
+	assert := assert.New(t)
+
+	midiIn := devtest.NewMockMIDIPort()
+	d := dev.NewMidiDevice(midiIn, devtest.NewMockMIDIPort())
+	xtouch := New(d)
+	defer devtest.RunDevice(t, xtouch.Run, d.IsConnected)()
+
+	var deltas []int
+	xtouch.Channels[2].Encoder.BindDelta(func(steps int) error { deltas = append(deltas, steps); return nil })
+	midiIn.SimulateReceive(midi.ControlChange(0, 18, 1))
+	midiIn.SimulateReceive(midi.ControlChange(0, 18, 67))
+	midiIn.SimulateReceive(midi.ControlChange(0, 17, 1))
+	assert.Equal([]int{1, -3}, deltas, "only turns of this strip's encoder should be reported")
+
+	var pan float64
+	acc := NewAccumulator(0, 1, 10).FineWhile(xtouch.Modify.SHIFT)
+	xtouch.Channels[0].Encoder.Accumulate(acc, func(v float64) error { pan = v; return nil })
+	midiIn.SimulateReceive(midi.ControlChange(0, 16, 3))
+	assert.InDelta(0.3, pan, 1e-9)
+
+	midiIn.SimulateReceive(midi.NoteOn(0, 70, 127))
+	midiIn.SimulateReceive(midi.ControlChange(0, 16, 1))
+	assert.InDelta(0.31, pan, 1e-9, "SHIFT should select fine steps")
+	midiIn.SimulateReceive(midi.NoteOn(0, 70, 0))
+
+	midiIn.SimulateReceive(midi.ControlChange(0, 16, 63))
+	assert.Equal(1.0, pan, "the value is clamped to the range")
+	pan = -1
+	midiIn.SimulateReceive(midi.ControlChange(0, 16, 1))
+	assert.Equal(-1.0, pan, "turns that leave the value unchanged are not reported")
+
+	acc.Set(0.5)
+	assert.Equal(0.5, acc.Value())
+	acc.SetFineResolution(1000).SetFine(true)
+	v, changed := acc.Add(-1)
+	assert.True(changed)
+	assert.InDelta(0.499, v, 1e-9)
+}
+
+func TestFaderTouch(t *testing.T) {
+	assert := assert.New(t)
+
+	midiIn := devtest.NewMockMIDIPort()
+	midiOut := devtest.NewMockMIDIPort()
+	d := dev.NewMidiDevice(midiIn, midiOut)
+	xtouch := New(d)
+	defer devtest.RunDevice(t, xtouch.Run, d.IsConnected)()
+
+	fader := xtouch.Channels[2].Fader
+	var touches, releases int
+	fader.Touch.Bind(func() error { touches++; return nil })
+	fader.Release.Bind(func() error { releases++; return nil })
+
+	midiIn.SimulateReceive(midi.NoteOn(0, 106, 127))
+	assert.Equal(1, touches)
+	assert.True(fader.Touched())
+
+	assert.NoError(fader.Set(1000))
+	assert.NoError(fader.Set(2000))
+	assert.Empty(midiOut.GetSentMessages(), "a touched fader must not be moved")
+
+	midiIn.SimulateReceive(midi.NoteOn(0, 106, 0))
+	assert.Equal(1, releases)
+	assert.False(fader.Touched())
+	sent := midiOut.GetSentMessages()
+	if assert.Len(sent, 1, "the last held move should be applied on release") {
+		var ch uint8
+		var rel int16
+		var abs uint16
+		assert.True(sent[0].GetPitchBend(&ch, &rel, &abs))
+		assert.Equal(uint8(2), ch)
+		assert.Equal(uint16(2000), abs)
+	}
+
+	midiIn.SimulateReceive(midi.NoteOn(0, 106, 127))
+	midiIn.SimulateReceive(midi.NoteOn(0, 106, 0))
+	assert.Len(midiOut.GetSentMessages(), 1, "releasing without a held move sends nothing")
+	assert.NoError(fader.Set(3000))
+	assert.Len(midiOut.GetSentMessages(), 2)
+}
+
+func TestTimecodeDisplay(t *testing.T) {
+	assert := assert.New(t)
+
+	midiIn := devtest.NewMockMIDIPort()
+	midiOut := devtest.NewMockMIDIPort()
+	xtouch := New(dev.NewMidiDevice(midiIn, midiOut))
+	display := xtouch.Timecode
+
+	assert.Equal(segB|segC, Segments('1'))
+	assert.Equal(Segments('A'), Segments('a'), "letters without a glyph of their own borrow the other case")
+	assert.Equal(uint8(0), Segments('~'))
+
+	// Every digit starts out unknown, so the first update sends all twelve.
+	assert.NoError(display.SetAssignment("rE"))
+	sent := midiOut.GetSentMessages()
+	assert.Len(sent, numDigits)
+	var ch, cc, val uint8
+	assert.True(sent[0].GetControlChange(&ch, &cc, &val))
+	assert.Equal([]uint8{96, segE | segG}, []uint8{cc, val})
+	assert.True(sent[1].GetControlChange(&ch, &cc, &val))
+	assert.Equal([]uint8{97, Segments('E')}, []uint8{cc, val})
+
+	// Only the digits that change are sent, and lit dots use the upper CC range. The leading hours digits stay blank.
+	assert.NoError(display.SetSMPTE(0, 0, 1, 0))
+	sent = midiOut.GetSentMessages()[numDigits:]
+	ccs := map[uint8]uint8{}
+	for _, msg := range sent {
+		assert.True(msg.GetControlChange(&ch, &cc, &val))
+		ccs[cc] = val
+	}
+	zero := Segments('0')
+	assert.Equal(map[uint8]uint8{
+		ccSegmentDot + 4: zero, ccSegment + 5: zero,
+		ccSegmentDot + 6: zero, ccSegment + 7: zero,
+		ccSegmentDot + 8: Segments('1'),
+		ccSegment + 9:    zero, ccSegment + 10: zero, ccSegment + 11: zero,
+	}, ccs)
+
+	assert.Error(display.SetAssignment("abc"))
+	assert.NoError(display.SetTimecode("12.34"))
+	assert.NoError(display.SetDot(0, true))
+	assert.Error(display.SetDigit(numDigits, 0))
+}
+
+func TestJogWheel(t *testing.T) {
+	assert := assert.New(t)
+
+	midiIn := devtest.NewMockMIDIPort()
+	midiOut := devtest.NewMockMIDIPort()
+	d := dev.NewMidiDevice(midiIn, midiOut)
+	xtouch := New(d)
+	defer devtest.RunDevice(t, xtouch.Run, d.IsConnected)()
+
+	jog := xtouch.JogWheel
+	clock := time.Unix(0, 0)
+	jog.now = func() time.Time { return clock }
+
+	var events []JogEvent
+	jog.Bind(func(e JogEvent) error {
+		events = append(events, e)
+		return nil
+	})
+
+	midiIn.SimulateReceive(midi.ControlChange(0, 60, 1))
+	clock = clock.Add(50 * time.Millisecond)
+	midiIn.SimulateReceive(midi.ControlChange(0, 60, 1))
+	clock = clock.Add(10 * time.Millisecond)
+	midiIn.SimulateReceive(midi.ControlChange(0, 60, 2))
+	if assert.Len(events, 3) {
+		assert.Equal(1, events[0].Delta)
+		assert.Equal(Shuttle, events[0].Mode)
+		assert.InDelta(4, events[0].Velocity, 1e-9, "a turn from rest starts at the slowest moving speed")
+		assert.InDelta(12, events[1].Velocity, 1e-9)
+		assert.InDelta(106, events[2].Velocity, 1e-9, "faster turns raise the estimate")
+		assert.Equal(2, events[2].Delta)
+	}
+
+	clock = clock.Add(10 * time.Millisecond)
+	midiIn.SimulateReceive(midi.ControlChange(0, 60, 65))
+	if assert.Len(events, 4) {
+		assert.Equal(-1, events[3].Delta)
+		assert.InDelta(-4, events[3].Velocity, 1e-9, "reversing restarts from rest")
+	}
+
+	// SCRUB toggles the mode and lights its LED.
+	midiIn.SimulateReceive(midi.NoteOn(0, 101, 127))
+	assert.Equal(Scrub, jog.Mode())
+	sent := midiOut.GetSentMessages()
+	if assert.Len(sent, 1) {
+		assert.Equal(midi.NoteOn(0, 101, 127).Bytes(), sent[0].Bytes())
+	}
+	midiIn.SimulateReceive(midi.ControlChange(0, 60, 1))
+	if assert.Len(events, 5) {
+		assert.Equal(Scrub, events[4].Mode)
+	}
+	midiIn.SimulateReceive(midi.NoteOn(0, 101, 127))
+	assert.Equal(Shuttle, jog.Mode())
+}
+
+func TestMeter(t *testing.T) {
+	assert := assert.New(t)
+
+	midiIn := devtest.NewMockMIDIPort()
+	midiOut := devtest.NewMockMIDIPort()
+	xtouch := New(dev.NewMidiDevice(midiIn, midiOut))
+	meter := xtouch.Channels[3].Meter
+
+	assert.NoError(meter.Send(0.5))
+	assert.NoError(meter.SetClip(true))
+	assert.Error(meter.Send(-0.1))
+	assert.Error(meter.Send(1.1))
+	sent := midiOut.GetSentMessages()
+	if assert.Len(sent, 2) {
+		assert.Equal(midi.AfterTouch(0, 0x34).Bytes(), sent[0].Bytes())
+		assert.Equal(midi.AfterTouch(0, 0x3E).Bytes(), sent[1].Bytes())
+	}
+
+	assert.Equal(uint8(0), MeterSegments(-70))
+	assert.Equal(uint8(1), MeterSegments(-60))
+	assert.Equal(uint8(6), MeterSegments(-10))
+	assert.Equal(uint8(8), MeterSegments(3))
+}
+
+func TestMeterEngine(t *testing.T) {
+	assert := assert.New(t)
+
+	midiIn := devtest.NewMockMIDIPort()
+	midiOut := devtest.NewMockMIDIPort()
+	xtouch := New(dev.NewMidiDevice(midiIn, midiOut))
+
+	engine := NewMeterEngine(Ballistics{Decay: 10, PeakHold: time.Second, ClipLevel: 0, ClipHold: 2 * time.Second},
+		xtouch.Channels[0].Meter, xtouch.Channels[1].Meter)
+	clock := time.Unix(0, 0)
+	engine.now = func() time.Time { return clock }
+
+	// tick returns the channel pressure values sent by one tick.
+	tick := func() []uint8 {
+		before := len(midiOut.GetSentMessages())
+		assert.NoError(engine.Tick())
+		var values []uint8
+		for _, msg := range midiOut.GetSentMessages()[before:] {
+			var ch, v uint8
+			assert.True(msg.GetAfterTouch(&ch, &v))
+			values = append(values, v)
+		}
+		return values
+	}
+
+	assert.Empty(tick(), "silent meters send nothing")
+
+	assert.NoError(engine.SetDB(0, -6))
+	assert.NoError(engine.SetDB(0, -30), "lower levels do not pull the meter down")
+	assert.Equal([]uint8{0x07}, tick())
+	clock = clock.Add(time.Second)
+	assert.Equal([]uint8{0x07}, tick(), "peaks are held, and resent so the surface does not decay them")
+	clock = clock.Add(time.Second)
+	assert.Equal([]uint8{0x05}, tick(), "after the hold the meter falls at the decay rate")
+	clock = clock.Add(10 * time.Second)
+	assert.Equal([]uint8{0x00}, tick())
+	assert.Empty(tick())
+
+	assert.NoError(engine.SetLinear(1, 1))
+	assert.Equal([]uint8{0x18, 0x1E}, tick(), "full scale lights every segment and the clip indicator")
+	clock = clock.Add(2 * time.Second)
+	assert.Equal([]uint8{0x16, 0x1F}, tick(), "the clip indicator clears after its hold")
+
+	assert.NoError(engine.SetDB(1, 1))
+	tick()
+	engine.ResetClips()
+	assert.Contains(tick(), uint8(0x1F))
+	assert.Error(engine.SetDB(2, 0))
+}
+
+func TestAbbreviate(t *testing.T) {
+	assert := assert.New(t)
+
+	for name, want := range map[string]string{
+		"Kick":            "Kick",
+		"Vocals Lead":     "Vox Ld",
+		"Vocal Double":    "Vox Dbl",
+		"Overheads Left":  "OH L",
+		"AcousticGuitar2": "AcGtr2",
+		"Tom 10":          "Tom 10",
+		"Hi-Hat Close 2":  "HiHtCl2",
+		"drum_room_far":   "DrmRmFr",
+		"Sampler Track 3": "SmpTrk3",
+		"12345678":        "123456~",
+		"A B C D E F G H": "ABCDEF~",
+	} {
+		got := Abbreviate(name, 7)
+		assert.Equal(want, got, name)
+		assert.LessOrEqual(len(got), 7, name)
+	}
+}
+
+func TestScribbleDiff(t *testing.T) {
+	assert := assert.New(t)
+
+	midiIn := devtest.NewMockMIDIPort()
+	midiOut := devtest.NewMockMIDIPort()
+	xtouch := New(dev.NewMidiDevice(midiIn, midiOut))
+	scribble := xtouch.Channels[1].Scribble
+
+	assert.NoError(scribble.ChangeColor(Blue).ChangeInversion(true, false).ChangeTopMessage("Vocals Lead").Set())
+	assert.NoError(scribble.Set())
+	sent := midiOut.GetSentMessages()
+	if assert.Len(sent, 1, "an unchanged strip is not resent") {
+		assert.Equal([]byte{0xf0, 0x00, 0x00, 0x66, 0x58, 0x21, 0x14, 'V', 'o', 'x', ' ', 'L', 'd', 0x00,
+			' ', ' ', ' ', ' ', ' ', ' ', ' ', 0xf7}, sent[0].Bytes())
+	}
+
+	assert.NoError(scribble.ChangeInversion(false, true).Set())
+	sent = midiOut.GetSentMessages()
+	if assert.Len(sent, 2) {
+		assert.Equal(byte(0x24), sent[1].Bytes()[6])
+	}
+}
+
+func TestSurfaceGroup(t *testing.T) {
+	assert := assert.New(t)
+
+	mainOut := devtest.NewMockMIDIPort()
+	extOut := devtest.NewMockMIDIPort()
+	mainUnit := New(dev.NewMidiDevice(devtest.NewMockMIDIPort(), mainOut))
+	ext := NewExtender(dev.NewMidiDevice(devtest.NewMockMIDIPort(), extOut))
+	assert.Len(ext.Channels, 8)
+
+	group := NewSurfaceGroup(mainUnit, ext)
+	assert.Equal(16, group.Len())
+	assert.Same(mainUnit.Channels[7], group.Strip(7))
+	assert.Same(ext.Channels[0], group.Strip(8))
+	unit, strip, err := group.Locate(9)
+	assert.NoError(err)
+	assert.Equal([]int{1, 1}, []int{unit, strip})
+
+	assert.NoError(group.Reorder(1, 0))
+	assert.Same(ext.Channels[0], group.Strip(0))
+	assert.Same(mainUnit.Channels[0], group.Strip(8))
+	unit, strip, err = group.Locate(9)
+	assert.NoError(err)
+	assert.Equal([]int{0, 1}, []int{unit, strip})
+	_, _, err = group.Locate(16)
+	assert.Error(err)
+	assert.Error(group.Reorder(0, 0))
+	assert.Error(group.Reorder(0))
+
+	// Extenders address their scribble strips with their own header.
+	assert.NoError(group.Strip(2).Scribble.ChangeTopMessage("Ext").Set())
+	assert.Empty(mainOut.GetSentMessages())
+	if sent := extOut.GetSentMessages(); assert.Len(sent, 1) {
+		assert.Equal([]byte{0xf0, 0x00, 0x00, 0x66, 0x59, 0x22}, sent[0].Bytes()[:6])
+	}
+}
+
+func TestConnection(t *testing.T) {
+	assert := assert.New(t)
+
+	midiIn := devtest.NewMockMIDIPort()
+	midiOut := devtest.NewMockMIDIPort()
+	d := dev.NewMidiDevice(midiIn, midiOut)
+	xtouch := New(d)
+	var states []ConnectionState
+	xtouch.BindConnection(func(s ConnectionState) error { states = append(states, s); return nil })
+	defer devtest.RunDevice(t, xtouch.Run, d.IsConnected)()
+	assert.Equal(Connecting, xtouch.Connection())
+
+	assert.NoError(xtouch.Channels[3].Rec.LED.On.Set())
+	assert.NoError(xtouch.Channels[3].Fader.Set(5000))
+	assert.NoError(xtouch.Channels[3].Scribble.ChangeTopMessage("Bass").Set())
+	before := len(midiOut.GetSentMessages())
+
+	respond := func() {
+		midiIn.SimulateReceive(midi.SysEx(append(handshakeResponsePrefix(deviceIDXTouch), 'S', 'N')))
+	}
+	restored := func(sent []midi.Message) (led, fader, scribble bool) {
+		for _, msg := range sent {
+			var ch, key, vel uint8
+			var rel int16
+			var abs uint16
+			switch {
+			case msg.GetNoteOn(&ch, &key, &vel):
+				led = led || (key == 3 && vel == 127)
+			case msg.GetPitchBend(&ch, &rel, &abs):
+				fader = fader || (ch == 3 && abs == 5000)
+			case msg.Is(midi.SysExMsg):
+				scribble = scribble || bytes.Contains(msg.Bytes(), []byte("Bass"))
+			}
+		}
+		return led, fader, scribble
+	}
+
+	respond()
+	assert.Equal(Online, xtouch.Connection())
+	led, fader, scribble := restored(midiOut.GetSentMessages()[before:])
+	assert.True(led, "LEDs should be restored when the surface comes online")
+	assert.True(fader, "faders should be restored when the surface comes online")
+	assert.True(scribble, "scribble strips should be restored when the surface comes online")
+
+	respond()
+	assert.Equal([]ConnectionState{Online}, states, "only changes of state are reported")
+
+	xtouch.handshakeMutex.Lock()
+	xtouch.lastResponse = time.Now().Add(-2 * responseTimeout)
+	xtouch.handshakeMutex.Unlock()
+	assert.True(xtouch.checkLiveness(), "a silent surface is still pinged")
+	assert.Equal(Lost, xtouch.Connection())
+
+	before = len(midiOut.GetSentMessages())
+	respond()
+	assert.Equal([]ConnectionState{Online, Lost, Online}, states)
+	led, _, _ = restored(midiOut.GetSentMessages()[before:])
+	assert.True(led, "state should be restored again after the connection was lost")
+}
+
+func TestShadow(t *testing.T) {
+	assert := assert.New(t)
+
+	midiIn := devtest.NewMockMIDIPort()
+	midiOut := devtest.NewMockMIDIPort()
+	d := dev.NewMidiDevice(midiIn, midiOut)
+	xtouch := New(d)
+	defer devtest.RunDevice(t, xtouch.Run, d.IsConnected)()
+	strip := xtouch.Channels[0]
+	sent := func() int { return len(midiOut.GetSentMessages()) }
+
+	// Refresh resends everything whether or not the surface already shows it, as when it comes online.
+	assert.NoError(xtouch.Refresh())
+	assert.Greater(sent(), 100)
+	start := sent()
+
+	// Redundant sends are suppressed.
+	assert.NoError(strip.Solo.LED.On.Set())
+	assert.NoError(strip.Solo.LED.Set(true))
+	assert.NoError(strip.Encoder.Ring.Set(0.5))
+	assert.NoError(strip.Encoder.Ring.Set(0.5))
+	assert.NoError(strip.Fader.Set(8000))
+	assert.NoError(strip.Fader.Set(8000))
+	assert.Equal(start+4, sent(), "one LED, two ring and one fader message")
+
+	// A fader moved by hand is moved back.
+	midiIn.SimulateReceive(midi.Pitchbend(0, 100))
+	assert.NoError(strip.Fader.Set(8000))
+	assert.Equal(start+5, sent())
+
+	pageA := xtouch.Snapshot()
+	assert.NoError(strip.Solo.LED.Off.Set())
+	assert.NoError(strip.Mute.LED.On.Set())
+	assert.NoError(strip.Scribble.ChangeTopMessage("Page B").Set())
+	pageB := xtouch.Snapshot()
+
+	before := sent()
+	assert.NoError(xtouch.Restore(pageA))
+	swapped := midiOut.GetSentMessages()[before:]
+	assert.Len(swapped, 3, "only the two LEDs and the scribble strip differ")
+	var ch, key, vel uint8
+	if assert.True(swapped[1].GetNoteOn(&ch, &key, &vel)) {
+		assert.Equal([]uint8{8, 127}, []uint8{key, vel}, "solo is lit again")
+	}
+	assert.Equal("", strip.Scribble.topMessage, "the scribble strip's staged text is restored too")
+
+	before = sent()
+	assert.NoError(xtouch.Restore(pageB))
+	assert.Len(midiOut.GetSentMessages()[before:], 3)
+	assert.NoError(xtouch.Restore(pageB))
+	assert.Len(midiOut.GetSentMessages()[before:], 3, "restoring what is shown sends nothing")
+
+	other := New(dev.NewMidiDevice(devtest.NewMockMIDIPort(), devtest.NewMockMIDIPort()))
+	assert.Error(other.Restore(pageA))
+}